@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newBackupCmd 创建 backup 子命令, 增量地将目录加密备份到另一个目录
+func newBackupCmd() *cobra.Command {
+	runner := cli.NewBackupRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "backup <src> <dest>",
+		Short:        "Encrypt new and changed files from src into dest and record a snapshot",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.SourceDir = args[0]
+			runner.DestDir = args[1]
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().StringVar(&runner.IndexPath, "index", "", "Local index of file hashes (default: in the user cache directory)")
+	cmd.Flags().IntVar(&runner.WorkFactor, "work-factor", runner.WorkFactor, "scrypt work factor (logN) used when encrypting")
+	cmd.Flags().StringVarP(&runner.Compress, "compress", "z", runner.Compress, "Compress before encrypting: none, gzip, zstd or auto (skips already-compressed inputs)")
+	cmd.Flags().Lookup("compress").NoOptDefVal = "auto"
+	cmd.Flags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs)")
+	cmd.Flags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable progress bars")
+
+	return cmd
+}
+
+// newRestoreCmd 创建 restore 子命令, 从备份目录还原某个快照
+func newRestoreCmd() *cobra.Command {
+	runner := cli.NewRestoreRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "restore <dest> [target-dir]",
+		Short:        "Restore a snapshot from a backup directory, or list its snapshots",
+		SilenceUsage: true,
+		Args:         cobra.RangeArgs(1, 2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.DestDir = args[0]
+			if len(args) > 1 {
+				runner.TargetDir = args[1]
+			}
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&runner.SnapshotID, "snapshot", "s", "", "Snapshot to restore (default: the latest)")
+	cmd.Flags().BoolVarP(&runner.List, "list", "l", false, "List the snapshots in the backup directory")
+	cmd.Flags().BoolVarP(&runner.Force, "force", "f", false, "Overwrite existing files in the target directory")
+	cmd.Flags().IntVar(&runner.MaxWorkFactor, "max-work-factor", runner.MaxWorkFactor, "Reject files whose scrypt work factor (logN) exceeds this value")
+	cmd.Flags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs)")
+	cmd.Flags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable progress bars")
+
+	return cmd
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newBenchCmd 创建 bench 子命令, 测量各工作因子下的密钥派生耗时
+func newBenchCmd() *cobra.Command {
+	runner := cli.NewBenchRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "bench",
+		Short:        "Measure how long scrypt key derivation takes for each work factor",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().IntVar(&runner.MinFactor, "min", runner.MinFactor, "Smallest work factor (logN) to measure")
+	cmd.Flags().IntVar(&runner.MaxFactor, "max", runner.MaxFactor, "Largest work factor (logN) to measure")
+	cmd.Flags().DurationVar(&runner.Limit, "limit", runner.Limit, "Stop once a single derivation takes longer than this")
+
+	return cmd
+}
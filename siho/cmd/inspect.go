@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newVerifyCmd 创建 verify 子命令, 检查文件完整性和密码是否正确
+func newVerifyCmd() *cobra.Command {
+	runner := cli.NewVerifyRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "verify <files...>",
+		Short:        "Check that encrypted files are intact and the password is correct",
+		SilenceUsage: true,
+		Args:         cobra.ArbitraryArgs, // 也可以通过 --files-from 指定输入
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().IntVar(&runner.MaxWorkFactor, "max-work-factor", runner.MaxWorkFactor, "Reject files whose scrypt work factor (logN) exceeds this value")
+	cmd.Flags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs)")
+	cmd.Flags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable progress bars")
+	cmd.Flags().BoolVar(&runner.JSON, "json", false, "Print one JSON record per file to stdout instead of coloured lines")
+	addSelectionFlags(cmd, &runner.Select)
+
+	return cmd
+}
+
+// newInfoCmd 创建 info 子命令, 查看 age 头部信息
+func newInfoCmd() *cobra.Command {
+	runner := cli.NewInfoRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "info <files...>",
+		Short:        "Show the recipient types and scrypt work factor of encrypted files",
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().BoolVar(&runner.JSON, "json", false, "Print one JSON record per file to stdout instead of coloured lines")
+
+	return cmd
+}
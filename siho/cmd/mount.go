@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newMountCmd 创建 mount 子命令, 通过 FUSE 只读地浏览加密目录
+func newMountCmd() *cobra.Command {
+	runner := cli.NewMountRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "mount <encrypted-dir> <mountpoint>",
+		Short:        "Mount a directory of encrypted files read-only, decrypting each file on first open",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.SourceDir = args[0]
+			runner.Mountpoint = args[1]
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().IntVar(&runner.MaxWorkFactor, "max-work-factor", runner.MaxWorkFactor, "Reject files whose scrypt work factor (logN) exceeds this value")
+	cmd.Flags().StringVar(&runner.MaxSize, "max-size", runner.MaxSize, "Limit on decrypted plaintext held in memory; larger files cannot be opened (e.g. 512M, 0 for no limit)")
+	cmd.Flags().BoolVar(&runner.Debug, "debug", false, "Print FUSE debug logs")
+
+	return cmd
+}
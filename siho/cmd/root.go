@@ -12,7 +12,7 @@ import (
 func Execute() {
 	if err := newRootCmd().Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "\nfor more information, try '--help'\n")
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
 
@@ -26,8 +26,8 @@ func newRootCmd() *cobra.Command {
 		Use:   "siho <files...>",
 		Short: "Securely encrypt and decrypt files with ease",
 
-		SilenceUsage: true,                  // 禁止 在出现错误时, 自动打印用法信息 Usage
-		Args:         cobra.MinimumNArgs(1), // 最少 1 个位置参数
+		SilenceUsage: true,                // 禁止 在出现错误时, 自动打印用法信息 Usage
+		Args:         cobra.ArbitraryArgs, // 也可以通过 --files-from 指定输入
 
 		// RunE 是执行入口函数, 它允许返回 error, 是 cobra 的推荐的实践
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -46,8 +46,25 @@ func newRootCmd() *cobra.Command {
 		},
 	}
 
+	// 子命令
+	cmd.AddCommand(newVerifyCmd(), newInfoCmd(), newBenchCmd(), newTextCmd(), newVaultCmd(), newMountCmd(), newSplitCmd(), newCombineCmd(), newBackupCmd(), newRestoreCmd())
+
 	cmd.Flags().StringVarP(&runner.OutputDir, "output-dir", "o", "", "Specify the directory path to store the output results")
 	cmd.Flags().BoolVarP(&runner.Decrypt, "decrypt", "d", false, "Enable decryption mode to restore encrypted files")
+	cmd.Flags().BoolVarP(&runner.Force, "force", "f", false, "Overwrite existing output files")
+	cmd.Flags().BoolVarP(&runner.InPlace, "in-place", "i", false, "Write output next to each input and remove the input once it is verified")
+	cmd.Flags().BoolVarP(&runner.Armor, "armor", "a", false, "Write PEM-style ASCII-armored output that can be pasted as text")
+	cmd.Flags().StringVarP(&runner.Compress, "compress", "z", "none", "Compress before encrypting: none, gzip, zstd or auto (skips already-compressed inputs)")
+	cmd.Flags().Lookup("compress").NoOptDefVal = "auto"
+	cmd.Flags().IntVar(&runner.WorkFactor, "work-factor", runner.WorkFactor, "scrypt work factor (logN) used for encryption; see 'siho bench'")
+	cmd.Flags().IntVar(&runner.MaxWorkFactor, "max-work-factor", runner.MaxWorkFactor, "Reject encrypted files whose scrypt work factor (logN) exceeds this value")
+	cmd.Flags().BoolVar(&runner.Obfuscate, "obfuscate-names", false, "Use random output names; the original name is restored on decryption")
+	cmd.Flags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs); use 1 on a single HDD")
+	cmd.Flags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable per-file progress bars and throughput")
+	cmd.Flags().BoolVar(&runner.JSON, "json", false, "Print one JSON record per file to stdout instead of coloured lines")
+	cmd.Flags().BoolVar(&runner.SkipExisting, "skip-existing", false, "Skip inputs whose output already exists, to resume an interrupted run")
+	addSelectionFlags(cmd, &runner.Select)
+	cmd.Flags().BoolVar(&runner.Shred, "shred", false, "Overwrite plaintext before removing it in --in-place mode (unreliable on SSDs and copy-on-write filesystems)")
 
 	return cmd
 }
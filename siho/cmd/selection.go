@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// addSelectionFlags 注册挑选输入文件的通用参数
+func addSelectionFlags(cmd *cobra.Command, sel *cli.SelectionFlags) {
+	cmd.Flags().StringVar(&sel.FilesFrom, "files-from", "", "Read input paths from a file, one per line ('-' for stdin)")
+	cmd.Flags().BoolVarP(&sel.Null, "null", "0", false, "Paths in --files-from are NUL-separated (as produced by 'find -print0')")
+	cmd.Flags().BoolVarP(&sel.Recursive, "recursive", "r", false, "Descend into directory arguments")
+	cmd.Flags().StringArrayVar(&sel.Include, "include", nil, "Only process files matching this glob (repeatable)")
+	cmd.Flags().StringArrayVar(&sel.Exclude, "exclude", nil, "Skip files and directories matching this glob (repeatable)")
+	cmd.Flags().StringVar(&sel.MinSize, "min-size", "", "Only process files at least this large (e.g. 10K, 5M, 1G)")
+	cmd.Flags().StringVar(&sel.MaxSize, "max-size", "", "Only process files at most this large (e.g. 10K, 5M, 1G)")
+	cmd.Flags().StringVar(&sel.OlderThan, "older-than", "", "Only process files modified longer ago than this (e.g. 1d, 12h)")
+	cmd.Flags().StringVar(&sel.NewerThan, "newer-than", "", "Only process files modified more recently than this (e.g. 1d, 12h)")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newSplitCmd 创建 split 子命令, 将文件加密给一次性私钥, 并把私钥拆分为 Shamir 份额
+func newSplitCmd() *cobra.Command {
+	runner := cli.NewSplitRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "split <files...>",
+		Short:        "Encrypt files to a fresh key and split that key into Shamir shares",
+		SilenceUsage: true,
+		Args:         cobra.ArbitraryArgs, // 也可以通过 --files-from 指定输入
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().IntVarP(&runner.Shares, "shares", "n", runner.Shares, "Number of shares to create")
+	cmd.Flags().IntVarP(&runner.Threshold, "threshold", "k", runner.Threshold, "Number of shares required to recover the key")
+	cmd.Flags().StringVar(&runner.SharesDir, "shares-dir", runner.SharesDir, "Directory to write the share files to")
+	cmd.Flags().StringVarP(&runner.OutputDir, "output-dir", "o", "", "Specify the directory path to store the encrypted files")
+	cmd.Flags().BoolVarP(&runner.Force, "force", "f", false, "Overwrite existing share and output files")
+	cmd.Flags().BoolVarP(&runner.Armor, "armor", "a", false, "Write PEM-style ASCII-armored output that can be pasted as text")
+	cmd.Flags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs)")
+	cmd.Flags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable progress bars")
+	addSelectionFlags(cmd, &runner.Select)
+
+	return cmd
+}
+
+// newCombineCmd 创建 combine 子命令, 用足够的份额还原私钥并解密文件
+func newCombineCmd() *cobra.Command {
+	runner := cli.NewCombineRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "combine --share <file>... [files...]",
+		Short:        "Rebuild the key from enough Shamir shares and decrypt files",
+		SilenceUsage: true,
+		Args:         cobra.ArbitraryArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&runner.SharePaths, "share", "s", nil, "Share file (repeatable, at least the threshold)")
+	cmd.Flags().StringVar(&runner.IdentityOutput, "identity-output", "", "Also write the recovered private key to this file")
+	cmd.Flags().StringVarP(&runner.OutputDir, "output-dir", "o", "", "Specify the directory path to store the decrypted files")
+	cmd.Flags().BoolVarP(&runner.Force, "force", "f", false, "Overwrite existing output files")
+	cmd.Flags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs)")
+	cmd.Flags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable progress bars")
+
+	return cmd
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newTextCmd 创建 text 子命令, 加密或解密文本片段
+func newTextCmd() *cobra.Command {
+	runner := cli.NewTextRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "text [text]",
+		Short:        "Encrypt a text snippet to armored output, or decrypt one to the terminal",
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				runner.Text = args[0]
+			}
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&runner.Decrypt, "decrypt", "d", false, "Decrypt armored text and print the plaintext")
+	cmd.Flags().BoolVarP(&runner.Editor, "editor", "e", false, "Read the text from a $VISUAL/$EDITOR buffer instead of stdin")
+	cmd.Flags().BoolVarP(&runner.Clip, "clip", "c", false, "Copy the result to the clipboard instead of printing it")
+	cmd.Flags().StringVar(&runner.ClipCmd, "clip-cmd", "", "Clipboard command that reads from stdin (default: $SIHO_CLIP_CMD or auto-detect)")
+	cmd.Flags().StringVar(&runner.PasteCmd, "paste-cmd", "", "Command that prints the clipboard, checked before clearing it (default: $SIHO_PASTE_CMD or derived from the clipboard command)")
+	cmd.Flags().DurationVar(&runner.ClearAfter, "clear-after", runner.ClearAfter, "Clear decrypted text from the clipboard after this long (0 keeps it)")
+	cmd.Flags().IntVar(&runner.WorkFactor, "work-factor", runner.WorkFactor, "scrypt work factor (logN) used for encryption")
+	cmd.Flags().IntVar(&runner.MaxWorkFactor, "max-work-factor", runner.MaxWorkFactor, "Reject ciphertext whose scrypt work factor (logN) exceeds this value")
+
+	return cmd
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"siho/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newVaultCmd 创建 vault 子命令, 管理加密给团队所有成员公钥的目录
+func newVaultCmd() *cobra.Command {
+	runner := cli.NewVaultRunner()
+
+	var cmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Manage a directory of files encrypted to every key in its .siho-recipients file",
+	}
+
+	cmd.PersistentFlags().StringVarP(&runner.Dir, "vault", "C", runner.Dir, "Vault directory")
+	cmd.PersistentFlags().IntVarP(&runner.Jobs, "jobs", "j", 0, "Number of files to process concurrently (default: number of CPUs)")
+	cmd.PersistentFlags().BoolVar(&runner.NoProgress, "no-progress", false, "Disable progress bars")
+
+	cmd.AddCommand(
+		newVaultInitCmd(runner),
+		newVaultKeygenCmd(runner),
+		newVaultAddCmd(runner),
+		newVaultGetCmd(runner),
+		newVaultRekeyCmd(runner),
+		newVaultStatusCmd(runner),
+	)
+
+	return cmd
+}
+
+func newVaultInitCmd(runner *cli.VaultRunner) *cobra.Command {
+	return &cobra.Command{
+		Use:          "init [public keys...]",
+		Short:        "Create the .siho-recipients file in the vault directory",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runner.Init(args)
+		},
+	}
+}
+
+func newVaultKeygenCmd(runner *cli.VaultRunner) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:          "keygen",
+		Short:        "Generate a new private key and print its public key",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runner.Keygen(output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the private key to this file instead of stdout")
+	return cmd
+}
+
+func newVaultAddCmd(runner *cli.VaultRunner) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "add <files...>",
+		Short:        "Encrypt files into the vault for every recipient",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+			return runner.Add()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&runner.Force, "force", "f", false, "Replace files that already exist in the vault")
+	addSelectionFlags(cmd, &runner.Select)
+	return cmd
+}
+
+func newVaultGetCmd(runner *cli.VaultRunner) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "get <files...>",
+		Short:        "Decrypt vault files with your private key",
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+			return runner.Get()
+		},
+	}
+
+	cmd.Flags().StringVarP(&runner.IdentityPath, "identity", "i", "", "Private key file (default: $SIHO_IDENTITY)")
+	cmd.Flags().StringVarP(&runner.OutputDir, "output-dir", "o", "", "Directory for the decrypted files (default: current directory)")
+	cmd.Flags().BoolVarP(&runner.Force, "force", "f", false, "Overwrite existing output files")
+	return cmd
+}
+
+func newVaultRekeyCmd(runner *cli.VaultRunner) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:          "rekey [files...]",
+		Short:        "Re-encrypt vault files to the current recipients",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.FilePaths = args
+			return runner.Rekey(all)
+		},
+	}
+
+	cmd.Flags().StringVarP(&runner.IdentityPath, "identity", "i", "", "Private key file able to decrypt the current files (default: $SIHO_IDENTITY)")
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "Re-encrypt every file, not only outdated and untracked ones")
+	return cmd
+}
+
+func newVaultStatusCmd(runner *cli.VaultRunner) *cobra.Command {
+	return &cobra.Command{
+		Use:          "status",
+		Short:        "Show which files are encrypted to an outdated recipient set",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runner.Status()
+		},
+	}
+}
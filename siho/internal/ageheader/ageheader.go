@@ -0,0 +1,139 @@
+package ageheader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"filippo.io/age/armor"
+)
+
+const (
+	versionLine  = "age-encryption.org/v1"
+	stanzaPrefix = "-> "
+	footerPrefix = "---"
+	columnsLimit = 64 // stanza 正文每行的最大列数, 不足一行时表示正文结束
+	maxLineSize  = 4096
+	maxStanzas   = 1024
+)
+
+// Stanza 头部中的一个 recipient stanza
+type Stanza struct {
+	Type string   // 类型, 如 scrypt, X25519
+	Args []string // 类型之后的参数
+}
+
+// Header 解析后的 age 头部
+type Header struct {
+	Stanzas []Stanza
+}
+
+// WorkFactor 返回 scrypt stanza 中的工作因子 (logN), 不存在时返回 0
+func (h *Header) WorkFactor() int {
+	for _, s := range h.Stanzas {
+		if s.Type != "scrypt" || len(s.Args) != 2 {
+			continue
+		}
+		logN, err := strconv.Atoi(s.Args[1])
+		if err != nil {
+			return 0
+		}
+		return logN
+	}
+	return 0
+}
+
+// Types 返回所有 stanza 的类型
+func (h *Header) Types() []string {
+	types := make([]string, 0, len(h.Stanzas))
+	for _, s := range h.Stanzas {
+		types = append(types, s.Type)
+	}
+	return types
+}
+
+// Parse 从 r 中读取并解析 age 头部, 不需要任何密钥
+func Parse(r io.Reader) (*Header, error) {
+	br := bufio.NewReaderSize(r, maxLineSize)
+
+	line, err := readLine(br)
+	if err != nil {
+		return nil, fmt.Errorf("读取版本行失败: %w", err)
+	}
+	if line != versionLine {
+		return nil, errors.New("不是 age 加密文件")
+	}
+
+	h := &Header{}
+	for {
+		line, err := readLine(br)
+		if err != nil {
+			return nil, fmt.Errorf("读取头部失败: %w", err)
+		}
+
+		if strings.HasPrefix(line, footerPrefix) {
+			break
+		}
+		if !strings.HasPrefix(line, stanzaPrefix) {
+			return nil, fmt.Errorf("无法识别的头部行: %q", line)
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, stanzaPrefix))
+		if len(fields) == 0 {
+			return nil, errors.New("stanza 缺少类型")
+		}
+		h.Stanzas = append(h.Stanzas, Stanza{Type: fields[0], Args: fields[1:]})
+		if len(h.Stanzas) > maxStanzas {
+			return nil, errors.New("stanza 数量过多")
+		}
+
+		// 跳过 stanza 正文, 直到遇到不满一行的行
+		for {
+			body, err := readLine(br)
+			if err != nil {
+				return nil, fmt.Errorf("读取 stanza 正文失败: %w", err)
+			}
+			if len(body) < columnsLimit {
+				break
+			}
+		}
+	}
+
+	if len(h.Stanzas) == 0 {
+		return nil, errors.New("头部中没有 recipient stanza")
+	}
+	return h, nil
+}
+
+// readLine 读取一行 (不含换行符), 超过长度限制时返回错误
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errors.New("头部行过长")
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(string(line), "\n"), nil
+}
+
+// NewReader 返回二进制 age 数据的 reader, 若输入是 ASCII 装甲格式则自动解除装甲
+func NewReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+
+	// 与 age 命令行一致, 允许装甲文本前有空白字符
+	const maxWhitespace = 1024
+	peek, _ := br.Peek(maxWhitespace + len(armor.Header))
+	trimmed := bytes.TrimLeft(peek, " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte(armor.Header)) {
+		return armor.NewReader(br)
+	}
+	return br
+}
@@ -0,0 +1,112 @@
+package ageheader
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// encrypt 生成一个真实的 age 文件, armored 为 true 时输出装甲格式
+func encrypt(t *testing.T, armored bool, recipients ...age.Recipient) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var dst io.Writer = &buf
+	var aw io.WriteCloser
+	if armored {
+		aw = armor.NewWriter(&buf)
+		dst = aw
+	}
+	w, err := age.Encrypt(dst, recipients...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "hello")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if aw != nil {
+		if err := aw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	scrypt, err := age.NewScryptRecipient("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrypt.SetWorkFactor(10)
+
+	var x25519 []age.Recipient
+	for range 3 {
+		id, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		x25519 = append(x25519, id.Recipient())
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantTypes  []string
+		wantFactor int
+	}{
+		{"scrypt", encrypt(t, false, scrypt), []string{"scrypt"}, 10},
+		{"single x25519", encrypt(t, false, x25519[0]), []string{"X25519"}, 0},
+		{"several x25519", encrypt(t, false, x25519...), []string{"X25519", "X25519", "X25519"}, 0},
+		{"armored scrypt", encrypt(t, true, scrypt), []string{"scrypt"}, 10},
+		{"armored after whitespace", append([]byte("\n  \n"), encrypt(t, true, x25519[0])...), []string{"X25519"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Parse(NewReader(bytes.NewReader(tt.data)))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := h.Types(); !slices.Equal(got, tt.wantTypes) {
+				t.Errorf("Types() = %v, want %v", got, tt.wantTypes)
+			}
+			if got := h.WorkFactor(); got != tt.wantFactor {
+				t.Errorf("WorkFactor() = %d, want %d", got, tt.wantFactor)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	body := strings.Repeat("A", columnsLimit)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", "读取版本行失败"},
+		{"not age", "hello world\n", "不是 age 加密文件"},
+		{"no stanzas", "age-encryption.org/v1\n--- mac\n", "没有 recipient stanza"},
+		{"unknown line", "age-encryption.org/v1\nhello\n--- mac\n", "无法识别的头部行"},
+		{"stanza without type", "age-encryption.org/v1\n-> \n\n--- mac\n", "缺少类型"},
+		{"truncated stanza body", "age-encryption.org/v1\n-> X25519 abc\n" + body + "\n", "读取 stanza 正文失败"},
+		{"missing footer", "age-encryption.org/v1\n-> X25519 abc\nshort\n", "读取头部失败"},
+		{"line too long", "age-encryption.org/v1\n-> X25519 " + strings.Repeat("a", maxLineSize) + "\n", "头部行过长"},
+		{"too many stanzas", "age-encryption.org/v1\n" + strings.Repeat("-> X25519 abc\nshort\n", maxStanzas+1) + "--- mac\n", "stanza 数量过多"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// live 记录尚未提交或放弃的临时文件, 供收到中断信号时统一清理
+var live = struct {
+	sync.Mutex
+	files map[*File]struct{}
+}{files: make(map[*File]struct{})}
+
+// TempPrefix 临时文件的名称前缀, 便于识别崩溃后残留的文件
+const TempPrefix = ".siho-tmp-"
+
+// File 先写入同目录下的临时文件, 提交时再原子地重命名到目标路径
+type File struct {
+	*os.File
+	path string      // 最终的目标路径
+	done atomic.Bool // 是否已提交或放弃, 可能被信号处理协程并发访问
+}
+
+// Create 在目标路径所在目录中创建临时文件
+func Create(path string) (*File, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := os.CreateTemp(dir, TempPrefix+base+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+
+	af := &File{File: f, path: path}
+	live.Lock()
+	live.files[af] = struct{}{}
+	live.Unlock()
+	return af, nil
+}
+
+// AbortAll 放弃所有未提交的临时文件, 用于进程被中断时避免留下写了一半的文件
+func AbortAll() {
+	live.Lock()
+	files := make([]*File, 0, len(live.files))
+	for f := range live.files {
+		files = append(files, f)
+	}
+	live.Unlock()
+
+	for _, f := range files {
+		f.Abort()
+	}
+}
+
+// forget 从未提交列表中移除
+func (f *File) forget() {
+	live.Lock()
+	delete(live.files, f)
+	live.Unlock()
+}
+
+// Commit 将数据刷入磁盘并把临时文件重命名为目标文件
+// overwrite 为 false 时, 若目标文件已存在则返回 os.ErrExist
+func (f *File) Commit(overwrite bool) error {
+	if f.done.Load() {
+		return errors.New("临时文件已提交或已放弃")
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Abort()
+		return fmt.Errorf("同步临时文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		f.Abort()
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+
+	tmpPath := f.Name()
+	if err := publish(tmpPath, f.path, overwrite); err != nil {
+		f.Abort()
+		return err
+	}
+	f.done.Store(true)
+	f.forget()
+
+	syncDir(filepath.Dir(f.path))
+	return nil
+}
+
+// Retarget 在提交前更换目标路径, 用于写完内容后才能确定名称的文件
+// 新路径需要与临时文件位于同一文件系统, 否则提交时重命名会失败
+func (f *File) Retarget(path string) {
+	f.path = path
+}
+
+// Abort 放弃写入并删除临时文件, 已提交时不做任何事
+func (f *File) Abort() {
+	if !f.done.CompareAndSwap(false, true) {
+		return
+	}
+	f.forget()
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// publish 把临时文件发布到目标路径
+func publish(tmpPath, path string, overwrite bool) error {
+	if overwrite {
+		if err := os.Rename(tmpPath, path); err != nil {
+			return fmt.Errorf("重命名临时文件失败: %w", err)
+		}
+		return nil
+	}
+
+	// 硬链接在目标已存在时会失败, 借此实现不覆盖的原子提交
+	err := os.Link(tmpPath, path)
+	if err == nil {
+		os.Remove(tmpPath)
+		return nil
+	}
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("输出文件已存在: %s: %w", path, os.ErrExist)
+	}
+
+	// 部分文件系统 (如 FAT) 不支持硬链接, 退化为先检查再重命名
+	if _, statErr := os.Lstat(path); statErr == nil {
+		return fmt.Errorf("输出文件已存在: %s: %w", path, os.ErrExist)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("重命名临时文件失败: %w", err)
+	}
+	return nil
+}
+
+// syncDir 尽力将目录项的变更刷入磁盘
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,149 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommit(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  bool // 目标文件是否已存在
+		overwrite bool
+		wantErr   error
+		want      string // 提交后目标文件的内容
+	}{
+		{"new file", false, false, nil, "new"},
+		{"no clobber", true, false, os.ErrExist, "old"},
+		{"overwrite", true, true, nil, "new"},
+		{"overwrite new file", false, true, nil, "new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out.txt")
+			if tt.existing {
+				if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err := Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.WriteString("new"); err != nil {
+				t.Fatal(err)
+			}
+
+			err = f.Commit(tt.overwrite)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Commit(%v) err = %v, want %v", tt.overwrite, err, tt.wantErr)
+			}
+			assertContent(t, path, tt.want)
+			// 提交成功或失败后都不留下临时文件
+			assertNoTemp(t, dir)
+		})
+	}
+}
+
+func TestRetarget(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Create(filepath.Join(dir, "pending"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("content")
+
+	// 写完内容后才确定名称, 目标可以在同一文件系统的其他目录中
+	path := filepath.Join(sub, "named")
+	f.Retarget(path)
+	if err := f.Commit(false); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	assertContent(t, path, "content")
+	assertNoTemp(t, dir)
+	if _, err := os.Lstat(filepath.Join(dir, "pending")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("original target was created: %v", err)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+
+	f, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("partial")
+	f.Abort()
+
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("target exists after Abort: %v", err)
+	}
+	assertNoTemp(t, dir)
+
+	// 放弃之后不能再提交, 再次放弃不做任何事
+	if err := f.Commit(true); err == nil {
+		t.Error("Commit after Abort succeeded")
+	}
+	f.Abort()
+}
+
+func TestAbortAll(t *testing.T) {
+	dir := t.TempDir()
+	committed, err := Create(filepath.Join(dir, "done.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := committed.Commit(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := Create(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 只清理未提交的临时文件, 已提交的文件保持不变
+	AbortAll()
+	assertNoTemp(t, dir)
+	assertContent(t, filepath.Join(dir, "done.txt"), "")
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), data, want)
+	}
+}
+
+func assertNoTemp(t *testing.T, dir string) {
+	t.Helper()
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), TempPrefix) {
+			t.Errorf("temporary file left behind: %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,311 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"siho/internal/cryptor"
+	"siho/internal/handler"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	KeyFile      = "siho-backup.key_enc" // 用密码加密保存的仓库密钥, 同时用于校验密码
+	objectsDir   = "objects"             // 按内容寻址的加密文件
+	snapshotsDir = "snapshots"           // 加密的快照清单
+	encSuffix    = "_enc"
+	keySize      = 32
+	keyVersion   = 2
+
+	// snapshotIDLayout 快照 ID 的时间格式, 按字典序排列即按时间排列
+	snapshotIDLayout = "20060102T150405Z"
+)
+
+// Entry 快照中单个文件的记录
+type Entry struct {
+	Path    string    `json:"path"` // 相对于备份源的路径, 以 / 分隔
+	Size    int64     `json:"size"`
+	Mode    uint32    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+	Object  string    `json:"object"` // 内容对应的对象名
+}
+
+// Snapshot 一次备份的清单, 加密后保存在备份目录中
+type Snapshot struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"` // 备份源的绝对路径
+	Files  []Entry   `json:"files"`
+}
+
+// NewSnapshot 以当前时间创建空快照
+func NewSnapshot(source string) *Snapshot {
+	now := time.Now().UTC()
+	return &Snapshot{ID: now.Format(snapshotIDLayout), Time: now, Source: source}
+}
+
+// Cryptor 备份目录中加密对象和快照使用的接口
+type Cryptor interface {
+	handler.Cryptor
+	EncryptTo(dst io.Writer, inputPath string, tee io.Writer) error
+	EncryptStream(dst io.Writer, src io.Reader) error
+	DecryptStream(dst io.Writer, src io.Reader) error
+}
+
+// repoKey 密钥文件中用密码加密保存的内容
+// 对象和快照都加密给随机生成的 X25519 私钥, 只有解开密钥文件时需要派生一次密码
+type repoKey struct {
+	Version   int    `json:"version"`
+	Identity  string `json:"identity"`   // 加密对象和快照的私钥
+	NamingKey string `json:"naming_key"` // 计算对象名的 HMAC 密钥, 十六进制
+}
+
+// Repo 备份目标目录
+type Repo struct {
+	Dir   string
+	crypt Cryptor
+	key   []byte // 计算对象名的 HMAC 密钥, 避免对象名泄露明文的哈希
+}
+
+// Exists 判断目录是否已经是备份目录
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, KeyFile))
+	return err == nil
+}
+
+// Open 打开备份目录并用密码解开仓库密钥, create 为 true 时在目录不存在时初始化
+// 密码错误时在这里就会失败, 不会处理任何文件; opts 用于加密对象和快照
+func Open(dir string, pass *cryptor.PasswordCryptor, opts cryptor.Options, create bool) (*Repo, error) {
+	r := &Repo{Dir: dir}
+
+	f, err := os.Open(filepath.Join(dir, KeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, fmt.Errorf("%s 不是备份目录, 缺少 %s", dir, KeyFile)
+		}
+		return r, r.init(pass, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开备份密钥失败: %w", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if err := pass.DecryptStream(&buf, f); err != nil {
+		return nil, fmt.Errorf("解密备份密钥失败: %w", err)
+	}
+
+	var k repoKey
+	if err := json.Unmarshal(buf.Bytes(), &k); err != nil {
+		return nil, fmt.Errorf("解析备份密钥失败: %w", err)
+	}
+	if k.Version != keyVersion {
+		return nil, fmt.Errorf("不支持的备份密钥版本: %d", k.Version)
+	}
+	if r.key, err = hex.DecodeString(k.NamingKey); err != nil || len(r.key) != keySize {
+		return nil, errors.New("备份密钥中的命名密钥无效")
+	}
+	if r.crypt, err = cryptor.NewIdentityCryptor(k.Identity, opts); err != nil {
+		return nil, fmt.Errorf("备份密钥无效: %w", err)
+	}
+	return r, nil
+}
+
+// Cryptor 返回加密和解密对象使用的加密器
+func (r *Repo) Cryptor() Cryptor {
+	return r.crypt
+}
+
+// init 创建目录结构, 生成新的仓库密钥并用密码加密保存
+func (r *Repo) init(pass *cryptor.PasswordCryptor, opts cryptor.Options) error {
+	for _, dir := range []string{objectsDir, snapshotsDir} {
+		if err := os.MkdirAll(filepath.Join(r.Dir, dir), 0755); err != nil {
+			return fmt.Errorf("创建备份目录失败: %w", err)
+		}
+	}
+
+	r.key = make([]byte, keySize)
+	if _, err := rand.Read(r.key); err != nil {
+		return fmt.Errorf("生成备份密钥失败: %w", err)
+	}
+	identity, _, err := cryptor.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	if r.crypt, err = cryptor.NewIdentityCryptor(identity, opts); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(repoKey{Version: keyVersion, Identity: identity, NamingKey: hex.EncodeToString(r.key)})
+	if err != nil {
+		return fmt.Errorf("序列化备份密钥失败: %w", err)
+	}
+	return writeEncrypted(pass, filepath.Join(r.Dir, KeyFile), data)
+}
+
+// writeEncrypted 加密数据并原子地写入新文件
+func writeEncrypted(c Cryptor, path string, data []byte) error {
+	f, err := atomicfile.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if err := c.EncryptStream(f, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	return f.Commit(false)
+}
+
+// ObjectName 根据内容哈希计算对象名
+func (r *Repo) ObjectName(hash string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidObjectName 判断对象名是否为 ObjectName 生成的十六进制字符串
+func ValidObjectName(name string) bool {
+	b, err := hex.DecodeString(name)
+	return err == nil && len(b) == sha256.Size
+}
+
+// ObjectPath 返回对象文件的路径, 按名称前两位分目录存放
+func (r *Repo) ObjectPath(name string) string {
+	return filepath.Join(r.Dir, objectsDir, name[:2], name+encSuffix)
+}
+
+// HasObject 判断对象是否已存在
+func (r *Repo) HasObject(name string) bool {
+	_, err := os.Stat(r.ObjectPath(name))
+	return err == nil
+}
+
+// PutFile 将文件加密为对象并返回明文的 SHA-256
+// 加密时同时计算哈希, 对象名由实际加密的内容决定, 文件在备份过程中被修改也不会名实不符;
+// 对象已存在 (例如被并发写入的相同内容) 时丢弃这次加密的结果
+func (r *Repo) PutFile(srcPath string) (string, error) {
+	f, err := atomicfile.Create(filepath.Join(r.Dir, objectsDir, "object"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Abort()
+
+	h := sha256.New()
+	if err := r.crypt.EncryptTo(f, srcPath, h); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	path := r.ObjectPath(r.ObjectName(hash))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("创建对象目录失败: %w", err)
+	}
+	f.Retarget(path)
+	if err := f.Commit(false); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+	return hash, nil
+}
+
+// WriteSnapshot 加密并保存快照清单
+// 同一秒内已有快照时在 ID 后加上序号, 保证按字典序排列仍是时间顺序
+func (r *Repo) WriteSnapshot(s *Snapshot) error {
+	slices.SortFunc(s.Files, func(a, b Entry) int { return strings.Compare(a.Path, b.Path) })
+	base := s.ID
+	for seq := 2; ; seq++ {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("序列化快照失败: %w", err)
+		}
+		err = writeEncrypted(r.crypt, filepath.Join(r.Dir, snapshotsDir, s.ID+encSuffix), data)
+		if !errors.Is(err, os.ErrExist) || seq > 9 {
+			return err
+		}
+		s.ID = fmt.Sprintf("%s-%d", base, seq)
+	}
+}
+
+// Snapshots 按时间顺序返回所有快照的 ID
+func (r *Repo) Snapshots() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(r.Dir, snapshotsDir))
+	if err != nil {
+		return nil, fmt.Errorf("读取快照目录失败: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), encSuffix); ok && !strings.HasPrefix(id, atomicfile.TempPrefix) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// ReadSnapshot 解密快照清单, id 为空时读取最新的快照
+func (r *Repo) ReadSnapshot(id string) (*Snapshot, error) {
+	if id == "" {
+		ids, err := r.Snapshots()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, errors.New("备份目录中还没有快照")
+		}
+		id = ids[len(ids)-1]
+	}
+
+	f, err := os.Open(filepath.Join(r.Dir, snapshotsDir, id+encSuffix))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("快照不存在: %s", id)
+		}
+		return nil, fmt.Errorf("打开快照失败: %w", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if err := r.crypt.DecryptStream(&buf, f); err != nil {
+		return nil, fmt.Errorf("解密快照失败: %w", err)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(buf.Bytes(), &s); err != nil {
+		return nil, fmt.Errorf("解析快照失败: %w", err)
+	}
+	return &s, nil
+}
+
+// Scan 递归列出备份源中的普通文件, 返回绝对路径到相对路径的映射
+// 符号链接和 siho 的临时文件会被跳过
+func Scan(src string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("遍历目录 %s 失败: %w", path, err)
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), atomicfile.TempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		files[path] = filepath.ToSlash(rel)
+		return nil
+	})
+	return files, err
+}
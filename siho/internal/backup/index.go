@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"sync"
+	"time"
+)
+
+// IndexEntry 上次备份时文件的大小、修改时间和内容哈希
+type IndexEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash"`
+}
+
+// Index 保存在本机的文件索引, 大小和修改时间未变化的文件无需重新计算哈希
+// 索引包含明文的路径和哈希, 因此不放在备份目录中
+type Index struct {
+	path  string
+	mu    sync.Mutex
+	Files map[string]IndexEntry `json:"files"` // 相对路径 -> 记录
+}
+
+// DefaultIndexPath 返回用户缓存目录下的索引路径, 每对备份源和目标各有一个索引
+func DefaultIndexPath(src, dest string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定缓存目录, 请使用 --index 指定索引路径: %w", err)
+	}
+
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(absSrc + "\x00" + absDest))
+	return filepath.Join(cacheDir, "siho", "backup-"+hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// LoadIndex 读取索引, 文件不存在时返回空索引
+func LoadIndex(path string) (*Index, error) {
+	ix := &Index{path: path, Files: make(map[string]IndexEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ix, nil
+		}
+		return nil, fmt.Errorf("读取备份索引失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, fmt.Errorf("解析备份索引失败: %w", err)
+	}
+	if ix.Files == nil {
+		ix.Files = make(map[string]IndexEntry)
+	}
+	return ix, nil
+}
+
+// Lookup 文件的大小和修改时间与索引一致时返回记录的哈希
+func (ix *Index) Lookup(rel string, info fs.FileInfo) (string, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	e, ok := ix.Files[rel]
+	if !ok || e.Size != info.Size() || !e.ModTime.Equal(info.ModTime()) {
+		return "", false
+	}
+	return e.Hash, true
+}
+
+// Set 更新文件的记录, 可以被多个 worker 并发调用
+func (ix *Index) Set(rel string, info fs.FileInfo, hash string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.Files[rel] = IndexEntry{Size: info.Size(), ModTime: info.ModTime(), Hash: hash}
+}
+
+// Retain 只保留 keep 中的路径, 用于移除已删除文件的记录
+func (ix *Index) Retain(keep map[string]bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for rel := range ix.Files {
+		if !keep[rel] {
+			delete(ix.Files, rel)
+		}
+	}
+}
+
+// Save 原子地写入索引, 只有当前用户可读
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	data, err := json.Marshal(ix)
+	ix.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("序列化备份索引失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0700); err != nil {
+		return fmt.Errorf("创建索引目录失败: %w", err)
+	}
+
+	f, err := atomicfile.Create(ix.path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("写入备份索引失败: %w", err)
+	}
+	return f.Commit(true)
+}
@@ -0,0 +1,344 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"siho/internal/backup"
+	"siho/internal/cryptor"
+	"siho/internal/handler"
+	"sync"
+)
+
+// BackupRunner 存储 backup 子命令的选项参数
+type BackupRunner struct {
+	SourceDir  string // 备份源目录
+	DestDir    string // 备份目标目录
+	IndexPath  string // 本机索引路径, 为空时使用缓存目录
+	WorkFactor int    // 加密时的 scrypt 工作因子
+	Compress   string // 加密前的压缩算法
+	Jobs       int    // 并发 worker 数量
+	NoProgress bool   // 不显示进度条
+	password   string // 输入的密码
+}
+
+func NewBackupRunner() *BackupRunner {
+	return &BackupRunner{
+		WorkFactor: cryptor.DefaultWorkFactor,
+		Compress:   cryptor.CompressNone,
+	}
+}
+
+// Validate 校验参数并读取密码, 首次备份到目标目录时需要确认密码
+func (r *BackupRunner) Validate() error {
+	info, err := os.Stat(r.SourceDir)
+	if err != nil {
+		return fmt.Errorf("无法访问备份源: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("备份源不是目录: %s", r.SourceDir)
+	}
+	if inside, err := isWithin(r.DestDir, r.SourceDir); err != nil || inside {
+		return fmt.Errorf("备份目标不能位于备份源之内: %s", r.DestDir)
+	}
+	if err := cryptor.ValidateWorkFactor(r.WorkFactor); err != nil {
+		return err
+	}
+	if err := cryptor.CheckScryptMemory(r.WorkFactor); err != nil {
+		return err
+	}
+	if err := cryptor.ValidateCompression(r.Compress); err != nil {
+		return err
+	}
+	if r.Jobs < 0 {
+		return fmt.Errorf("--jobs 不能为负数: %d", r.Jobs)
+	}
+	if r.IndexPath == "" {
+		if r.IndexPath, err = backup.DefaultIndexPath(r.SourceDir, r.DestDir); err != nil {
+			return err
+		}
+	}
+
+	password, err := promptPassword(!backup.Exists(r.DestDir))
+	if err != nil {
+		return err
+	}
+	r.password = password
+	return nil
+}
+
+// Run 只加密新增或修改过的文件, 然后写入新的快照
+func (r *BackupRunner) Run() error {
+	pass, err := cryptor.NewPasswordCryptor(r.password, cryptor.Options{
+		WorkFactor: r.WorkFactor,
+		Compress:   r.Compress,
+	})
+	if err != nil {
+		return fmt.Errorf("初始化对称加密结构时出错: %w", err)
+	}
+
+	repo, err := backup.Open(r.DestDir, pass, cryptor.Options{Compress: r.Compress}, true)
+	if err != nil {
+		return err
+	}
+	c := repo.Cryptor()
+	index, err := backup.LoadIndex(r.IndexPath)
+	if err != nil {
+		return err
+	}
+
+	source, err := filepath.Abs(r.SourceDir)
+	if err != nil {
+		return err
+	}
+	files, err := backup.Scan(source)
+	if err != nil {
+		return err
+	}
+
+	// 大小和修改时间未变且对象仍然存在的文件直接记入快照
+	snapshot := backup.NewSnapshot(source)
+	var mu sync.Mutex
+	record := func(rel string, info os.FileInfo, object string) {
+		mu.Lock()
+		defer mu.Unlock()
+		snapshot.Files = append(snapshot.Files, backup.Entry{
+			Path:    rel,
+			Size:    info.Size(),
+			Mode:    uint32(info.Mode().Perm()),
+			ModTime: info.ModTime(),
+			Object:  object,
+		})
+	}
+
+	var pending []string
+	seen := make(map[string]bool, len(files))
+	for path, rel := range files {
+		seen[rel] = true
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("无法访问 %s: %w", path, err)
+		}
+		if hash, ok := index.Lookup(rel, info); ok {
+			if object := repo.ObjectName(hash); repo.HasObject(object) {
+				record(rel, info, object)
+				continue
+			}
+		}
+		pending = append(pending, path)
+	}
+	index.Retain(seen)
+
+	unchanged := len(snapshot.Files)
+	if len(pending) > 0 {
+		h := handler.NewHandler(pending, "", c, handler.Options{
+			Jobs:     r.Jobs,
+			Progress: showProgress(r.NoProgress),
+		})
+		err = h.HandleEach("Backed up", func(path string) error {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			hash, err := repo.PutFile(path)
+			if err != nil {
+				return err
+			}
+			index.Set(files[path], info, hash)
+			record(files[path], info, repo.ObjectName(hash))
+			return nil
+		})
+	}
+
+	// 部分文件失败时也保存已成功文件的快照和索引
+	if saveErr := index.Save(); saveErr != nil {
+		err = errors.Join(err, saveErr)
+	}
+	if saveErr := repo.WriteSnapshot(snapshot); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	successColor.Printf("Snapshot %s: %d files, %d unchanged\n", snapshot.ID, len(snapshot.Files), unchanged)
+	return err
+}
+
+// isWithin 判断 path 是否为 dir 或位于 dir 之内
+func isWithin(path, dir string) (bool, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false, nil
+	}
+	return filepath.IsLocal(rel) || rel == ".", nil
+}
+
+// RestoreRunner 存储 restore 子命令的选项参数
+type RestoreRunner struct {
+	DestDir       string // 备份目录
+	TargetDir     string // 还原到该目录
+	SnapshotID    string // 要还原的快照, 为空时使用最新的快照
+	List          bool   // 只列出快照
+	Force         bool   // 允许覆盖已存在的文件
+	MaxWorkFactor int    // 接受的最大工作因子
+	Jobs          int    // 并发 worker 数量
+	NoProgress    bool   // 不显示进度条
+	password      string // 输入的密码
+}
+
+func NewRestoreRunner() *RestoreRunner {
+	return &RestoreRunner{
+		MaxWorkFactor: cryptor.DefaultMaxWorkFactor,
+	}
+}
+
+// Validate 校验参数并读取密码
+func (r *RestoreRunner) Validate() error {
+	if !backup.Exists(r.DestDir) {
+		return fmt.Errorf("%s 不是备份目录, 缺少 %s", r.DestDir, backup.KeyFile)
+	}
+	if !r.List && r.TargetDir == "" {
+		return errors.New("未指定还原目录")
+	}
+	if err := cryptor.ValidateWorkFactor(r.MaxWorkFactor); err != nil {
+		return err
+	}
+	if r.Jobs < 0 {
+		return fmt.Errorf("--jobs 不能为负数: %d", r.Jobs)
+	}
+
+	password, err := promptPassword(false)
+	if err != nil {
+		return err
+	}
+	r.password = password
+	return nil
+}
+
+// Run 列出快照, 或将快照中的文件还原到目标目录
+func (r *RestoreRunner) Run() error {
+	pass, err := cryptor.NewPasswordCryptor(r.password, cryptor.Options{MaxWorkFactor: r.MaxWorkFactor})
+	if err != nil {
+		return fmt.Errorf("初始化对称加密结构时出错: %w", err)
+	}
+
+	repo, err := backup.Open(r.DestDir, pass, cryptor.Options{}, false)
+	if err != nil {
+		return err
+	}
+	c := repo.Cryptor()
+
+	if r.List {
+		ids, err := repo.Snapshots()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return nil
+	}
+
+	snapshot, err := repo.ReadSnapshot(r.SnapshotID)
+	if err != nil {
+		return err
+	}
+	if err := ensureOutputDir(r.TargetDir); err != nil {
+		return err
+	}
+
+	// 相同内容只解密一次, 再复制给其余路径
+	targets := make(map[string][]backup.Entry)
+	var objects []string
+	var errs []error
+	for _, e := range snapshot.Files {
+		if !filepath.IsLocal(filepath.FromSlash(e.Path)) || !backup.ValidObjectName(e.Object) {
+			errs = append(errs, fmt.Errorf("快照中的记录无效: %s", e.Path))
+			continue
+		}
+		path := repo.ObjectPath(e.Object)
+		if !repo.HasObject(e.Object) {
+			errs = append(errs, fmt.Errorf("对象缺失: %s (%s)", e.Path, e.Object))
+			continue
+		}
+		if _, ok := targets[path]; !ok {
+			objects = append(objects, path)
+		}
+		targets[path] = append(targets[path], e)
+	}
+	warnColor.Fprintf(os.Stderr, "Restoring snapshot %s (%d files)\n", snapshot.ID, len(snapshot.Files))
+
+	if len(objects) > 0 {
+		h := handler.NewHandler(objects, "", c, handler.Options{
+			Jobs:     r.Jobs,
+			Progress: showProgress(r.NoProgress),
+		})
+		err := h.HandleEachOutput("Restored", func(object string) (string, error) {
+			entries := targets[object]
+			first := filepath.Join(r.TargetDir, filepath.FromSlash(entries[0].Path))
+			if err := r.restoreFile(first, entries[0], func(w io.Writer) error {
+				return c.DecryptTo(object, w)
+			}); err != nil {
+				return first, err
+			}
+			for _, e := range entries[1:] {
+				path := filepath.Join(r.TargetDir, filepath.FromSlash(e.Path))
+				if err := r.restoreFile(path, e, func(w io.Writer) error {
+					return copyFile(first, w)
+				}); err != nil {
+					return path, err
+				}
+			}
+			return first, nil
+		})
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// restoreFile 通过 write 原子地写入还原的文件, 然后还原权限和修改时间
+func (r *RestoreRunner) restoreFile(path string, e backup.Entry, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	f, err := atomicfile.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	if e.Mode != 0 {
+		f.Chmod(os.FileMode(e.Mode).Perm())
+	}
+	if err := f.Commit(r.Force); err != nil {
+		return err
+	}
+	os.Chtimes(path, e.ModTime, e.ModTime)
+	return nil
+}
+
+// copyFile 将已还原的文件复制到 w
+func copyFile(path string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("复制文件失败: %w", err)
+	}
+	return nil
+}
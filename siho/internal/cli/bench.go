@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"siho/internal/cryptor"
+	"siho/internal/progress"
+	"time"
+)
+
+// BenchRunner 存储 bench 子命令的选项参数
+type BenchRunner struct {
+	MinFactor int           // 起始工作因子
+	MaxFactor int           // 结束工作因子
+	Limit     time.Duration // 单次耗时超过该值后停止测试更大的因子
+}
+
+func NewBenchRunner() *BenchRunner {
+	return &BenchRunner{
+		MinFactor: 10,
+		MaxFactor: cryptor.DefaultMaxWorkFactor,
+		Limit:     10 * time.Second,
+	}
+}
+
+// Validate 校验参数
+func (r *BenchRunner) Validate() error {
+	if err := cryptor.ValidateWorkFactor(r.MinFactor); err != nil {
+		return err
+	}
+	if err := cryptor.ValidateWorkFactor(r.MaxFactor); err != nil {
+		return err
+	}
+	if r.MinFactor > r.MaxFactor {
+		return fmt.Errorf("起始工作因子 %d 大于结束工作因子 %d", r.MinFactor, r.MaxFactor)
+	}
+	return nil
+}
+
+// Run 依次测量每个工作因子的密钥派生耗时
+func (r *BenchRunner) Run() error {
+	warnColor.Printf("%-6s %-10s %-12s %s\n", "logN", "N", "memory", "time")
+
+	for logN := r.MinFactor; logN <= r.MaxFactor; logN++ {
+		elapsed, err := cryptor.BenchmarkScrypt(logN)
+		if errors.Is(err, cryptor.ErrNotEnoughMemory) {
+			errorColor.Println(err)
+			fmt.Println("停止测试更大的工作因子")
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line := fmt.Sprintf("%-6d %-10d %-12s %s", logN, 1<<logN, progress.FormatBytes(cryptor.ScryptMemory(logN)), elapsed.Round(time.Millisecond))
+		if logN == cryptor.DefaultWorkFactor {
+			line += "  (default)"
+		}
+
+		if elapsed > r.Limit {
+			errorColor.Println(line)
+			fmt.Printf("单次耗时已超过 %s, 停止测试更大的工作因子\n", r.Limit)
+			break
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCandidates 未指定剪贴板命令时, 依次尝试的常见命令
+var clipboardCandidates = []string{
+	"wl-copy",
+	"xclip -selection clipboard",
+	"xsel --clipboard --input",
+	"pbcopy",
+	"clip.exe",
+}
+
+// pasteCommands 常见剪贴板命令对应的读取命令, 清除前用来检查剪贴板的内容
+var pasteCommands = map[string]string{
+	"wl-copy":                    "wl-paste --no-newline",
+	"xclip -selection clipboard": "xclip -selection clipboard -o",
+	"xsel --clipboard --input":   "xsel --clipboard --output",
+	"pbcopy":                     "pbpaste",
+	"clip.exe":                   "powershell.exe -NoProfile -Command Get-Clipboard",
+}
+
+// detectClipboardCmd 返回可用的剪贴板命令, 优先使用环境变量 SIHO_CLIP_CMD
+func detectClipboardCmd() (string, error) {
+	if cmd := os.Getenv("SIHO_CLIP_CMD"); cmd != "" {
+		return cmd, nil
+	}
+
+	for _, candidate := range clipboardCandidates {
+		name := strings.Fields(candidate)[0]
+		if _, err := exec.LookPath(name); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("未找到可用的剪贴板命令, 请通过 --clip-cmd 或 SIHO_CLIP_CMD 指定")
+}
+
+// detectPasteCmd 返回读取剪贴板的命令, 优先使用环境变量 SIHO_PASTE_CMD
+// 其次根据剪贴板命令推断, 无法推断时返回空字符串
+func detectPasteCmd(clipCmd string) string {
+	if cmd := os.Getenv("SIHO_PASTE_CMD"); cmd != "" {
+		return cmd
+	}
+	return pasteCommands[strings.Join(strings.Fields(clipCmd), " ")]
+}
+
+// copyToClipboard 将数据通过标准输入传给剪贴板命令
+func copyToClipboard(cmdLine string, data []byte) error {
+	fields := strings.Fields(cmdLine)
+	if len(fields) == 0 {
+		return errors.New("剪贴板命令为空")
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdin = strings.NewReader(string(data))
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("执行剪贴板命令 '%s' 失败: %w", cmdLine, err)
+	}
+	return nil
+}
+
+// readClipboard 执行读取命令, 返回剪贴板的内容
+func readClipboard(cmdLine string) ([]byte, error) {
+	fields := strings.Fields(cmdLine)
+	if len(fields) == 0 {
+		return nil, errors.New("读取剪贴板的命令为空")
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stderr = os.Stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("执行剪贴板命令 '%s' 失败: %w", cmdLine, err)
+	}
+	return data, nil
+}
+
+// editBuffer 打开 $VISUAL 或 $EDITOR 编辑临时文件, 返回编辑后的内容
+// initial 为编辑器中的初始内容
+func editBuffer(initial []byte) ([]byte, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// CreateTemp 以 0600 权限创建文件, 避免其他用户读取
+	f, err := os.CreateTemp("", "siho-text-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	if _, err := f.Write(initial); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("运行编辑器 '%s' 失败: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取编辑结果失败: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"siho/internal/cryptor"
+	"siho/internal/handler"
+)
+
+// VerifyRunner 存储 verify 子命令的选项参数
+type VerifyRunner struct {
+	FilePaths     []string // 待校验的文件路径列表
+	MaxWorkFactor int      // 接受的最大工作因子
+	Jobs          int      // 并发 worker 数量, 0 表示使用 CPU 核数
+	NoProgress    bool     // 不显示进度条
+	JSON          bool     // 以 JSON Lines 格式输出每个文件的结果
+	password      string   // 输入的密码
+
+	Select    SelectionFlags    // 挑选输入文件的选项
+	selection handler.Selection // 解析后的挑选规则
+}
+
+func NewVerifyRunner() *VerifyRunner {
+	return &VerifyRunner{
+		MaxWorkFactor: cryptor.DefaultMaxWorkFactor,
+	}
+}
+
+// Validate 校验参数并读取密码
+func (r *VerifyRunner) Validate() error {
+	if !r.Select.HasInput(r.FilePaths) {
+		return errors.New("未指定待校验的文件")
+	}
+
+	selection, err := r.Select.Build()
+	if err != nil {
+		return err
+	}
+	r.selection = selection
+	if err := cryptor.ValidateWorkFactor(r.MaxWorkFactor); err != nil {
+		return err
+	}
+	if r.Jobs < 0 {
+		return fmt.Errorf("--jobs 不能为负数: %d", r.Jobs)
+	}
+
+	password, err := promptPassword(false)
+	if err != nil {
+		return err
+	}
+	r.password = password
+	return nil
+}
+
+// Run 将每个文件解密到 io.Discard, 逐个报告结果
+func (r *VerifyRunner) Run() error {
+	c, err := cryptor.NewPasswordCryptor(r.password, cryptor.Options{MaxWorkFactor: r.MaxWorkFactor})
+	if err != nil {
+		return fmt.Errorf("初始化对称加密结构时出错: %w", err)
+	}
+
+	h := handler.NewHandler(r.FilePaths, "", c, handler.Options{
+		Jobs:     r.Jobs,
+		Progress: showProgress(r.NoProgress),
+
+		Selection: r.selection,
+		JSON:      r.JSON,
+	})
+	return h.HandleVerify()
+}
+
+// InfoRunner 存储 info 子命令的选项参数
+type InfoRunner struct {
+	FilePaths []string // 待查看的文件路径列表
+	JSON      bool     // 以 JSON Lines 格式输出每个文件的结果
+}
+
+func NewInfoRunner() *InfoRunner {
+	return &InfoRunner{}
+}
+
+// Validate 校验参数
+func (r *InfoRunner) Validate() error {
+	if len(r.FilePaths) == 0 {
+		return errors.New("未指定待查看的文件")
+	}
+	return nil
+}
+
+// Run 解析头部信息, 不需要密码
+func (r *InfoRunner) Run() error {
+	h := handler.NewHandler(r.FilePaths, "", nil, handler.Options{JSON: r.JSON})
+	return h.HandleInfo()
+}
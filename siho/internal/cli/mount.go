@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"siho/internal/cryptor"
+	"siho/internal/handler"
+	"siho/internal/mount"
+	"syscall"
+)
+
+// MountRunner 存储 mount 子命令的选项参数
+type MountRunner struct {
+	SourceDir     string // 加密目录
+	Mountpoint    string // 挂载点
+	MaxWorkFactor int    // 接受的最大工作因子
+	MaxSize       string // 内存中明文的总量上限, 如 512M, 0 表示不限制
+	Debug         bool   // 输出 FUSE 调试日志
+	password      string // 输入的密码
+	maxSize       int64
+}
+
+func NewMountRunner() *MountRunner {
+	return &MountRunner{
+		MaxWorkFactor: cryptor.DefaultMaxWorkFactor,
+		MaxSize:       "1G",
+	}
+}
+
+// Validate 校验参数并读取密码
+func (r *MountRunner) Validate() error {
+	for _, dir := range []string{r.SourceDir, r.Mountpoint} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("无法访问目录: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("不是目录: %s", dir)
+		}
+	}
+	if err := cryptor.ValidateWorkFactor(r.MaxWorkFactor); err != nil {
+		return err
+	}
+	maxSize, err := handler.ParseSize(r.MaxSize)
+	if err != nil {
+		return err
+	}
+	r.maxSize = maxSize
+
+	password, err := promptPassword(false)
+	if err != nil {
+		return err
+	}
+	r.password = password
+	return nil
+}
+
+// Run 挂载加密目录, 阻塞直到收到中断信号或被外部卸载
+func (r *MountRunner) Run() error {
+	c, err := cryptor.NewPasswordCryptor(r.password, cryptor.Options{MaxWorkFactor: r.MaxWorkFactor})
+	if err != nil {
+		return fmt.Errorf("初始化对称加密结构时出错: %w", err)
+	}
+
+	server, err := mount.Mount(r.SourceDir, r.Mountpoint, c, mount.Options{MaxSize: r.maxSize, Debug: r.Debug})
+	if err != nil {
+		return err
+	}
+	defer server.Cleanup()
+	successColor.Printf("Mounted %s -> %s (read-only), press Ctrl+C to unmount\n", r.SourceDir, r.Mountpoint)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	done := make(chan struct{})
+	go func() {
+		server.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-sigs:
+			// 挂载点仍被占用时卸载会失败, 此时保持挂载, 等待下一次中断
+			if err := server.Unmount(); err != nil {
+				warnColor.Fprintf(os.Stderr, "Unmount failed: %v (close open files and press Ctrl+C again)\n", err)
+				continue
+			}
+			<-done
+			successColor.Printf("Unmounted %s\n", r.Mountpoint)
+			return nil
+		}
+	}
+}
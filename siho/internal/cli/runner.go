@@ -7,33 +7,111 @@ import (
 	"siho/internal/cryptor"
 	"siho/internal/handler"
 
+	"github.com/fatih/color"
 	"golang.org/x/term"
 )
 
+var (
+	successColor = color.New(color.FgGreen)
+	warnColor    = color.New(color.FgCyan)
+	errorColor   = color.New(color.FgRed)
+)
+
 // Runner 存储选项参数
 type Runner struct {
 	FilePaths []string // 待处理的文件路径列表
 	Decrypt   bool     // 解密模式
 	OutputDir string   // 指定输出目录
+	Force     bool     // 允许覆盖已存在的输出文件
+	InPlace   bool     // 原地加密/解密, 成功后删除原文件
+	Shred     bool     // 删除明文前先覆盖写入
+	Obfuscate bool     // 使用随机的输出文件名
+	Armor     bool     // 输出 ASCII 装甲文本
+	Compress  string   // 加密前的压缩算法
+	JSON      bool     // 以 JSON Lines 格式输出每个文件的结果
 	password  string   // 输入的密码
+
+	WorkFactor    int // 加密时的 scrypt 工作因子
+	MaxWorkFactor int // 解密时接受的最大工作因子
+
+	Jobs         int  // 并发 worker 数量, 0 表示使用 CPU 核数
+	NoProgress   bool // 不显示进度条
+	SkipExisting bool // 跳过输出已存在的文件
+
+	Select    SelectionFlags    // 挑选输入文件的选项
+	selection handler.Selection // 解析后的挑选规则
 }
 
 func NewRunner() *Runner {
-	return &Runner{}
+	return &Runner{
+		WorkFactor:    cryptor.DefaultWorkFactor,
+		MaxWorkFactor: cryptor.DefaultMaxWorkFactor,
+	}
 }
 
 // Validate 校验参数, 协调执行各个校验步骤
 func (r *Runner) Validate() error {
 	// 校验待处理的路径
-	if len(r.FilePaths) == 0 {
+	if !r.Select.HasInput(r.FilePaths) {
 		return errors.New("未指定待处理的文件")
 	}
 
+	selection, err := r.Select.Build()
+	if err != nil {
+		return err
+	}
+	r.selection = selection
+
+	// 校验原地模式的参数组合
+	if r.InPlace && r.OutputDir != "" {
+		return errors.New("--in-place 不能与 --output-dir 同时使用")
+	}
+	if r.Shred && (!r.InPlace || r.Decrypt) {
+		return errors.New("--shred 仅可用于原地加密 (--in-place)")
+	}
+	if r.Obfuscate && r.Decrypt {
+		return errors.New("--obfuscate-names 仅可用于加密")
+	}
+	if err := cryptor.ValidateCompression(r.Compress); err != nil {
+		return err
+	}
+	if r.Compress != "" && r.Compress != cryptor.CompressNone && r.Decrypt {
+		return errors.New("--compress 仅可用于加密, 解密时会自动解压")
+	}
+	if r.Armor && r.Decrypt {
+		return errors.New("--armor 仅可用于加密, 解密时会自动识别装甲格式")
+	}
+
+	if r.Jobs < 0 {
+		return fmt.Errorf("--jobs 不能为负数: %d", r.Jobs)
+	}
+	if r.SkipExisting && r.Force {
+		return errors.New("--skip-existing 不能与 --force 同时使用")
+	}
+
+	// 校验工作因子, 避免 age 在非法值上 panic
+	if err := cryptor.ValidateWorkFactor(r.WorkFactor); err != nil {
+		return err
+	}
+	if err := cryptor.ValidateWorkFactor(r.MaxWorkFactor); err != nil {
+		return err
+	}
+	if !r.Decrypt {
+		if err := cryptor.CheckScryptMemory(r.WorkFactor); err != nil {
+			return err
+		}
+	}
+
 	// 获取并设置密码
 	if err := r.acquirePassword(); err != nil {
 		return err
 	}
 
+	// 原地模式直接写到输入文件旁边, 无需准备输出目录
+	if r.InPlace {
+		return nil
+	}
+
 	// 设置并准备输出目录
 	if err := r.setupAndPrepareOutputDir(); err != nil {
 		return err
@@ -45,12 +123,31 @@ func (r *Runner) Validate() error {
 // Run 执行核心逻辑
 func (r *Runner) Run() error {
 	// 1. 依赖注入
-	c, err := cryptor.NewPasswordCryptor(r.password)
+	c, err := cryptor.NewPasswordCryptor(r.password, cryptor.Options{
+		Overwrite:     r.Force,
+		WorkFactor:    r.WorkFactor,
+		MaxWorkFactor: r.MaxWorkFactor,
+		Armor:         r.Armor,
+		Compress:      r.Compress,
+	})
 	if err != nil {
 		return fmt.Errorf("初始化对称加密结构时出错: %w", err)
 	}
 
-	h := handler.NewHandler(r.FilePaths, r.OutputDir, c)
+	h := handler.NewHandler(r.FilePaths, r.OutputDir, c, handler.Options{
+		Force:   r.Force,
+		InPlace: r.InPlace,
+		Shred:   r.Shred,
+
+		ObfuscateNames: r.Obfuscate,
+
+		Jobs:         r.Jobs,
+		Progress:     showProgress(r.NoProgress),
+		SkipExisting: r.SkipExisting,
+
+		Selection: r.selection,
+		JSON:      r.JSON,
+	})
 
 	// 2. 执行操作
 	if r.Decrypt {
@@ -61,51 +158,80 @@ func (r *Runner) Run() error {
 
 // acquirePassword 提示用户输入并设置密码
 func (r *Runner) acquirePassword() error {
-	if r.Decrypt {
-		fmt.Print("请输入解密所需的密码:")
+	password, err := promptPassword(!r.Decrypt)
+	if err != nil {
+		return err
+	}
+	r.password = password
+	return nil
+}
+
+// promptPassword 从终端读取密码, confirm 为 true 时 (加密模式) 需要二次确认
+// 提示信息写到标准错误, 标准输入被重定向时改为从 /dev/tty 读取
+func promptPassword(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return "", fmt.Errorf("无法打开终端读取密码: %w", err)
+		}
+		defer tty.Close()
+		fd = int(tty.Fd())
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "请设定一个密码以用于加密:")
 	} else {
-		fmt.Print("请设定一个密码以用于加密:")
+		fmt.Fprint(os.Stderr, "请输入解密所需的密码:")
 	}
 
-	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	passwordBytes, err := term.ReadPassword(fd)
 	if err != nil {
-		return fmt.Errorf("读取密码失败: %w", err)
+		return "", fmt.Errorf("读取密码失败: %w", err)
 	}
-	fmt.Println() // 换行
+	fmt.Fprintln(os.Stderr) // 换行
 
 	password := string(passwordBytes)
 	if password == "" {
-		return errors.New("密码不能为空")
+		return "", errors.New("密码不能为空")
 	}
 
 	// 解密模式不需要二次确认
-	if r.Decrypt {
-		r.password = password
-		return nil
+	if !confirm {
+		return password, nil
 	}
 
 	// 加密模式需要二次确认
-	fmt.Print("请再次确认密码:")
-	confirmBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprint(os.Stderr, "请再次确认密码:")
+	confirmBytes, err := term.ReadPassword(fd)
 	if err != nil {
-		return fmt.Errorf("读取确认密码失败: %w", err)
+		return "", fmt.Errorf("读取确认密码失败: %w", err)
 	}
-	fmt.Println()
+	fmt.Fprintln(os.Stderr)
 
 	if password != string(confirmBytes) {
-		return errors.New("两次输入的密码不一致")
+		return "", errors.New("两次输入的密码不一致")
 	}
 
-	r.password = password
-	return nil
+	return password, nil
+}
+
+// ExitCode 根据错误类型返回进程的退出码
+func ExitCode(err error) int {
+	return handler.ExitCode(err)
+}
+
+// showProgress 仅在标准错误是终端时显示进度条
+func showProgress(disabled bool) bool {
+	return !disabled && term.IsTerminal(int(os.Stderr.Fd()))
 }
 
 // setupAndPrepareOutputDir 设置并准备输出目录
 func (r *Runner) setupAndPrepareOutputDir() error {
 	// 1. 如果输出目录未指定, 则设置默认值
 	if r.OutputDir == "" {
-		// 如果有多个文件，默认输出到专门的目录
-		if len(r.FilePaths) > 1 {
+		// 如果可能有多个文件，默认输出到专门的目录
+		if r.Select.MayMatchMany(r.FilePaths) {
 			if r.Decrypt {
 				r.OutputDir = "decrypted_result"
 			} else {
@@ -118,10 +244,15 @@ func (r *Runner) setupAndPrepareOutputDir() error {
 	}
 
 	// 2. 确保输出目录存在且是一个目录
-	info, err := os.Stat(r.OutputDir)
+	return ensureOutputDir(r.OutputDir)
+}
+
+// ensureOutputDir 确保输出目录存在且是一个目录, 不存在时创建
+func ensureOutputDir(dir string) error {
+	info, err := os.Stat(dir)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			if mkErr := os.MkdirAll(r.OutputDir, 0755); mkErr != nil {
+			if mkErr := os.MkdirAll(dir, 0755); mkErr != nil {
 				return fmt.Errorf("创建输出目录失败: %w", mkErr)
 			}
 			return nil // 创建成功
@@ -130,7 +261,7 @@ func (r *Runner) setupAndPrepareOutputDir() error {
 	}
 
 	if !info.IsDir() {
-		return fmt.Errorf("输出路径存在但不是目录: %s", r.OutputDir)
+		return fmt.Errorf("输出路径存在但不是目录: %s", dir)
 	}
 	return nil
 }
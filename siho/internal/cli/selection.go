@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"siho/internal/handler"
+)
+
+// SelectionFlags 存储挑选输入文件的选项参数, 大小和时长以字符串形式接收
+type SelectionFlags struct {
+	FilesFrom string   // 从文件读取路径列表
+	Null      bool     // 路径列表以 NUL 分隔
+	Recursive bool     // 递归处理目录
+	Include   []string // 包含的 glob 模式
+	Exclude   []string // 排除的 glob 模式
+	MinSize   string   // 最小文件大小, 如 10M
+	MaxSize   string   // 最大文件大小, 如 2G
+	OlderThan string   // 修改时间早于多久之前, 如 1d
+	NewerThan string   // 修改时间晚于多久之前, 如 12h
+}
+
+// HasInput 判断是否指定了任何输入来源
+func (f *SelectionFlags) HasInput(paths []string) bool {
+	return len(paths) > 0 || f.FilesFrom != ""
+}
+
+// MayMatchMany 判断输入是否可能展开为多个文件, 用于决定默认的输出目录
+func (f *SelectionFlags) MayMatchMany(paths []string) bool {
+	return len(paths) > 1 || f.FilesFrom != "" || f.Recursive
+}
+
+// Build 解析并校验选项, 生成 handler 使用的挑选规则
+func (f *SelectionFlags) Build() (handler.Selection, error) {
+	sel := handler.Selection{
+		FilesFrom: f.FilesFrom,
+		Null:      f.Null,
+		Recursive: f.Recursive,
+		Include:   f.Include,
+		Exclude:   f.Exclude,
+	}
+
+	if err := handler.ValidatePatterns(f.Include); err != nil {
+		return sel, err
+	}
+	if err := handler.ValidatePatterns(f.Exclude); err != nil {
+		return sel, err
+	}
+
+	var err error
+	if sel.MinSize, err = handler.ParseSize(f.MinSize); err != nil {
+		return sel, fmt.Errorf("--min-size: %w", err)
+	}
+	if sel.MaxSize, err = handler.ParseSize(f.MaxSize); err != nil {
+		return sel, fmt.Errorf("--max-size: %w", err)
+	}
+	if sel.MaxSize > 0 && sel.MinSize > sel.MaxSize {
+		return sel, fmt.Errorf("--min-size 大于 --max-size")
+	}
+	if sel.OlderThan, err = handler.ParseAge(f.OlderThan); err != nil {
+		return sel, fmt.Errorf("--older-than: %w", err)
+	}
+	if sel.NewerThan, err = handler.ParseAge(f.NewerThan); err != nil {
+		return sel, fmt.Errorf("--newer-than: %w", err)
+	}
+
+	return sel, nil
+}
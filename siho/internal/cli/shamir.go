@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"siho/internal/cryptor"
+	"siho/internal/handler"
+	"siho/internal/shamir"
+
+	"filippo.io/age"
+)
+
+// SplitRunner 存储 split 子命令的选项参数
+type SplitRunner struct {
+	FilePaths  []string // 待加密的文件
+	OutputDir  string   // 密文的输出目录
+	SharesDir  string   // 份额文件的输出目录
+	Shares     int      // 份额总数
+	Threshold  int      // 还原所需的最少份额数
+	Force      bool     // 允许覆盖已存在的文件
+	Armor      bool     // 输出 ASCII 装甲文本
+	Jobs       int      // 并发 worker 数量
+	NoProgress bool     // 不显示进度条
+
+	Select    SelectionFlags    // 挑选输入文件的选项
+	selection handler.Selection // 解析后的挑选规则
+}
+
+func NewSplitRunner() *SplitRunner {
+	return &SplitRunner{
+		SharesDir: ".",
+		Shares:    5,
+		Threshold: 3,
+	}
+}
+
+// Validate 校验参数并准备输出目录
+func (r *SplitRunner) Validate() error {
+	if !r.Select.HasInput(r.FilePaths) {
+		return errors.New("未指定待加密的文件")
+	}
+	if err := shamir.ValidateParams(r.Shares, r.Threshold); err != nil {
+		return err
+	}
+	if r.Jobs < 0 {
+		return fmt.Errorf("--jobs 不能为负数: %d", r.Jobs)
+	}
+
+	selection, err := r.Select.Build()
+	if err != nil {
+		return err
+	}
+	r.selection = selection
+
+	if r.OutputDir == "" {
+		r.OutputDir = "."
+		if r.Select.MayMatchMany(r.FilePaths) {
+			r.OutputDir = "encrypted_result"
+		}
+	}
+	if err := ensureOutputDir(r.OutputDir); err != nil {
+		return err
+	}
+	return ensureOutputDir(r.SharesDir)
+}
+
+// Run 生成一次性私钥, 先写出它的份额, 再把文件加密给对应的公钥
+// 份额写完之后才开始加密, 保证任何密文都能被还原
+func (r *SplitRunner) Run() error {
+	identity, recipient, err := cryptor.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	rcpt, err := cryptor.ParseRecipient(recipient)
+	if err != nil {
+		return err
+	}
+
+	parts, err := shamir.Split([]byte(identity), r.Shares, r.Threshold)
+	if err != nil {
+		return err
+	}
+	for i, part := range parts {
+		share := &shamir.Share{
+			Index:     i + 1,
+			Total:     r.Shares,
+			Threshold: r.Threshold,
+			Recipient: recipient,
+			Data:      part,
+		}
+		path := filepath.Join(r.SharesDir, fmt.Sprintf("siho-share-%d-of-%d.txt", share.Index, share.Total))
+		if err := writeSecretFile(path, share.Encode(), r.Force); err != nil {
+			return err
+		}
+		successColor.Printf("Share -> %s\n", path)
+	}
+	fmt.Fprintf(os.Stderr, "Public key: %s (any %d of %d shares can decrypt)\n", recipient, r.Threshold, r.Shares)
+
+	c := cryptor.NewKeyCryptor([]age.Recipient{rcpt}, nil, cryptor.Options{Overwrite: r.Force, Armor: r.Armor})
+	h := handler.NewHandler(r.FilePaths, r.OutputDir, c, handler.Options{
+		Force:     r.Force,
+		Jobs:      r.Jobs,
+		Progress:  showProgress(r.NoProgress),
+		Selection: r.selection,
+	})
+	return h.HandleEncrypt()
+}
+
+// CombineRunner 存储 combine 子命令的选项参数
+type CombineRunner struct {
+	SharePaths     []string // 份额文件
+	FilePaths      []string // 待解密的文件
+	OutputDir      string   // 解密结果的输出目录
+	IdentityOutput string   // 将还原出的私钥写入该文件
+	Force          bool     // 允许覆盖已存在的文件
+	Jobs           int      // 并发 worker 数量
+	NoProgress     bool     // 不显示进度条
+}
+
+func NewCombineRunner() *CombineRunner {
+	return &CombineRunner{}
+}
+
+// Validate 校验参数并准备输出目录
+func (r *CombineRunner) Validate() error {
+	if len(r.SharePaths) == 0 {
+		return errors.New("未指定份额文件, 请使用 --share")
+	}
+	if len(r.FilePaths) == 0 && r.IdentityOutput == "" {
+		return errors.New("未指定待解密的文件, 也未指定 --identity-output")
+	}
+	if r.Jobs < 0 {
+		return fmt.Errorf("--jobs 不能为负数: %d", r.Jobs)
+	}
+	if len(r.FilePaths) == 0 {
+		return nil
+	}
+
+	if r.OutputDir == "" {
+		r.OutputDir = "."
+		if len(r.FilePaths) > 1 {
+			r.OutputDir = "decrypted_result"
+		}
+	}
+	return ensureOutputDir(r.OutputDir)
+}
+
+// Run 用份额还原私钥, 然后按需保存私钥并解密文件
+func (r *CombineRunner) Run() error {
+	shares := make([]*shamir.Share, 0, len(r.SharePaths))
+	for _, path := range r.SharePaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取份额文件失败: %w", err)
+		}
+		share, err := shamir.ParseShare(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		shares = append(shares, share)
+	}
+
+	secret, err := shamir.CombineShares(shares)
+	if err != nil {
+		return err
+	}
+	identity, err := age.ParseX25519Identity(string(secret))
+	clear(secret)
+	if err != nil || identity.Recipient().String() != shares[0].Recipient {
+		return errors.New("还原出的私钥与份额记录的公钥不一致, 份额可能已损坏")
+	}
+	successColor.Printf("Recovered private key for %s\n", shares[0].Recipient)
+
+	if r.IdentityOutput != "" {
+		content := fmt.Sprintf("# public key: %s\n%s\n", shares[0].Recipient, identity)
+		if err := writeSecretFile(r.IdentityOutput, []byte(content), r.Force); err != nil {
+			return err
+		}
+		successColor.Printf("Private key -> %s\n", r.IdentityOutput)
+	}
+
+	if len(r.FilePaths) == 0 {
+		return nil
+	}
+
+	c := cryptor.NewKeyCryptor(nil, []age.Identity{identity}, cryptor.Options{Overwrite: r.Force})
+	h := handler.NewHandler(r.FilePaths, r.OutputDir, c, handler.Options{
+		Force:    r.Force,
+		Jobs:     r.Jobs,
+		Progress: showProgress(r.NoProgress),
+	})
+	return h.HandleDecrypt()
+}
+
+// writeSecretFile 原子地写入仅当前用户可读的文件, overwrite 为 false 时不覆盖已存在的文件
+func writeSecretFile(path string, data []byte, overwrite bool) error {
+	f, err := atomicfile.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return f.Commit(overwrite)
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"siho/internal/cryptor"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// TextRunner 存储 text 子命令的选项参数
+type TextRunner struct {
+	Text       string        // 命令行参数中给出的文本
+	Decrypt    bool          // 解密模式
+	Editor     bool          // 通过编辑器输入文本
+	Clip       bool          // 将结果复制到剪贴板
+	ClipCmd    string        // 剪贴板命令, 为空时自动检测
+	PasteCmd   string        // 读取剪贴板的命令, 清除前检查剪贴板是否仍是解密结果, 为空时根据 ClipCmd 推断
+	ClearAfter time.Duration // 解密结果复制到剪贴板后, 经过该时长自动清除, 0 表示不清除
+
+	WorkFactor    int // 加密时的 scrypt 工作因子
+	MaxWorkFactor int // 解密时接受的最大工作因子
+
+	input    []byte // 待处理的文本
+	password string // 输入的密码
+	copied   []byte // 复制到剪贴板的解密结果
+}
+
+func NewTextRunner() *TextRunner {
+	return &TextRunner{
+		ClearAfter:    30 * time.Second,
+		WorkFactor:    cryptor.DefaultWorkFactor,
+		MaxWorkFactor: cryptor.DefaultMaxWorkFactor,
+	}
+}
+
+// Validate 校验参数, 读取待处理的文本和密码
+func (r *TextRunner) Validate() error {
+	if r.Text != "" && r.Editor {
+		return errors.New("不能同时从参数和编辑器读取文本")
+	}
+	if err := cryptor.ValidateWorkFactor(r.WorkFactor); err != nil {
+		return err
+	}
+	if err := cryptor.ValidateWorkFactor(r.MaxWorkFactor); err != nil {
+		return err
+	}
+	if !r.Decrypt {
+		if err := cryptor.CheckScryptMemory(r.WorkFactor); err != nil {
+			return err
+		}
+	}
+
+	if r.Clip && r.ClipCmd == "" {
+		cmd, err := detectClipboardCmd()
+		if err != nil {
+			return err
+		}
+		r.ClipCmd = cmd
+	}
+	if r.Clip && r.PasteCmd == "" {
+		r.PasteCmd = detectPasteCmd(r.ClipCmd)
+	}
+
+	// 先读取文本, 再读取密码, 避免两者在终端上交错
+	if err := r.readInput(); err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(r.input)) == 0 {
+		return errors.New("待处理的文本为空")
+	}
+
+	password, err := promptPassword(!r.Decrypt)
+	if err != nil {
+		return err
+	}
+	r.password = password
+	return nil
+}
+
+// Run 执行加密或解密, 加密结果总是装甲文本
+func (r *TextRunner) Run() error {
+	c, err := cryptor.NewPasswordCryptor(r.password, cryptor.Options{
+		WorkFactor:    r.WorkFactor,
+		MaxWorkFactor: r.MaxWorkFactor,
+		Armor:         true,
+	})
+	if err != nil {
+		return fmt.Errorf("初始化对称加密结构时出错: %w", err)
+	}
+
+	var out bytes.Buffer
+	if r.Decrypt {
+		err = c.DecryptStream(&out, bytes.NewReader(r.input))
+	} else {
+		err = c.EncryptStream(&out, bytes.NewReader(r.input))
+	}
+	if err != nil {
+		return err
+	}
+
+	if !r.Clip {
+		os.Stdout.Write(out.Bytes())
+		if !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			fmt.Println()
+		}
+		return nil
+	}
+
+	if err := copyToClipboard(r.ClipCmd, out.Bytes()); err != nil {
+		return err
+	}
+	successColor.Fprintln(os.Stderr, "已复制到剪贴板")
+
+	// 密文无需清除, 明文在指定时长后从剪贴板中清除
+	if !r.Decrypt || r.ClearAfter <= 0 {
+		return nil
+	}
+	r.copied = out.Bytes()
+	return r.clearClipboardLater()
+}
+
+// readInput 按优先级从参数、编辑器或标准输入读取文本
+func (r *TextRunner) readInput() error {
+	if r.Text != "" {
+		r.input = []byte(r.Text)
+		return nil
+	}
+
+	var data []byte
+	var err error
+	if r.Editor {
+		data, err = editBuffer(nil)
+	} else {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintln(os.Stderr, "请输入文本, 以 Ctrl+D 结束:")
+		}
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("读取文本失败: %w", err)
+	}
+
+	// 编辑器和 echo 通常会追加一个换行, 加密密钥等短文本时不希望保留它
+	if !r.Decrypt {
+		data = bytes.TrimSuffix(data, []byte("\n"))
+		data = bytes.TrimSuffix(data, []byte("\r"))
+	}
+	r.input = data
+	return nil
+}
+
+// clearClipboardLater 等待指定时长后清空剪贴板, 收到 Ctrl+C 时立即清空
+// 清空前读取剪贴板, 只有内容仍是解密结果时才清空
+func (r *TextRunner) clearClipboardLater() error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	warnColor.Fprintf(os.Stderr, "将在 %s 后清除剪贴板 (Ctrl+C 立即清除)\n", r.ClearAfter)
+
+	select {
+	case <-time.After(r.ClearAfter):
+	case <-sigs:
+	}
+
+	defer clear(r.copied)
+
+	// 剪贴板已被用户复制的其他内容替换时保留它, 无法读取剪贴板时仍然清除, 避免明文残留
+	if r.PasteCmd == "" {
+		warnColor.Fprintln(os.Stderr, "无法确定读取剪贴板的命令, 直接清除 (可通过 --paste-cmd 或 SIHO_PASTE_CMD 指定)")
+	} else if current, err := readClipboard(r.PasteCmd); err != nil {
+		warnColor.Fprintf(os.Stderr, "读取剪贴板失败, 直接清除: %v\n", err)
+	} else {
+		// 部分命令读取时会增减末尾的换行, 比较时忽略
+		same := bytes.Equal(bytes.TrimRight(current, "\r\n"), bytes.TrimRight(r.copied, "\r\n"))
+		clear(current)
+		if !same {
+			warnColor.Fprintln(os.Stderr, "剪贴板内容已改变, 不再清除")
+			return nil
+		}
+	}
+
+	if err := copyToClipboard(r.ClipCmd, nil); err != nil {
+		return fmt.Errorf("清除剪贴板失败: %w", err)
+	}
+	successColor.Fprintln(os.Stderr, "剪贴板已清除")
+	return nil
+}
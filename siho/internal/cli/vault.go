@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"siho/internal/cryptor"
+	"siho/internal/handler"
+	"siho/internal/vault"
+
+	"filippo.io/age"
+)
+
+// VaultRunner 存储 vault 子命令的选项参数
+type VaultRunner struct {
+	Dir          string   // 保险库目录
+	IdentityPath string   // 私钥文件路径, 为空时读取 SIHO_IDENTITY
+	FilePaths    []string // 待处理的文件
+	OutputDir    string   // get 的输出目录
+	Force        bool     // 允许覆盖已存在的文件
+	Jobs         int      // 并发 worker 数量
+	NoProgress   bool     // 不显示进度条
+
+	Select SelectionFlags // add 时挑选输入文件的选项
+}
+
+func NewVaultRunner() *VaultRunner {
+	return &VaultRunner{Dir: "."}
+}
+
+// Init 创建保险库, pubkeys 为初始的团队公钥
+func (r *VaultRunner) Init(pubkeys []string) error {
+	if err := vault.Init(r.Dir, pubkeys); err != nil {
+		return err
+	}
+	successColor.Printf("已创建 %s\n", filepath.Join(r.Dir, vault.RecipientsFile))
+	if len(pubkeys) == 0 {
+		warnColor.Println("公钥列表为空, 请先添加团队成员的公钥 (每行一个)")
+	}
+	return nil
+}
+
+// Keygen 生成新的私钥并写入文件, 同时打印对应的公钥
+func (r *VaultRunner) Keygen(outputPath string) error {
+	identity, recipient, err := cryptor.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("# public key: %s\n%s\n", recipient, identity)
+	if outputPath == "" {
+		fmt.Print(content)
+	} else {
+		f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("创建私钥文件失败: %w", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			return fmt.Errorf("写入私钥文件失败: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Public key: %s\n", recipient)
+	return nil
+}
+
+// Add 将文件加密给保险库中的所有公钥, 写入保险库目录
+func (r *VaultRunner) Add() error {
+	if !r.Select.HasInput(r.FilePaths) {
+		return errors.New("未指定待加入保险库的文件")
+	}
+	selection, err := r.Select.Build()
+	if err != nil {
+		return err
+	}
+
+	v, err := vault.Open(r.Dir)
+	if err != nil {
+		return err
+	}
+
+	c := cryptor.NewKeyCryptor(v.Recipients, nil, cryptor.Options{Overwrite: r.Force})
+	h := handler.NewHandler(r.FilePaths, r.Dir, c, handler.Options{
+		Force:     r.Force,
+		Jobs:      r.Jobs,
+		Progress:  showProgress(r.NoProgress),
+		Selection: selection,
+		OnSuccess: func(_, outputPath string) { v.Record(outputPath) },
+	})
+
+	// 部分文件失败时也要保存已成功文件的记录
+	err = h.HandleEncrypt()
+	if saveErr := v.Save(); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+// Get 用私钥解密保险库中的文件
+func (r *VaultRunner) Get() error {
+	if len(r.FilePaths) == 0 {
+		return errors.New("未指定待解密的文件")
+	}
+
+	identities, err := r.loadIdentities()
+	if err != nil {
+		return err
+	}
+
+	if r.OutputDir == "" {
+		r.OutputDir = "."
+	}
+	if err := os.MkdirAll(r.OutputDir, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	c := cryptor.NewKeyCryptor(nil, identities, cryptor.Options{Overwrite: r.Force})
+	h := handler.NewHandler(r.FilePaths, r.OutputDir, c, handler.Options{
+		Force:    r.Force,
+		Jobs:     r.Jobs,
+		Progress: showProgress(r.NoProgress),
+	})
+	return h.HandleDecrypt()
+}
+
+// Rekey 将保险库中的文件重新加密给当前的公钥列表
+// 未指定文件时处理所有状态不是最新的文件
+func (r *VaultRunner) Rekey(all bool) error {
+	v, err := vault.Open(r.Dir)
+	if err != nil {
+		return err
+	}
+
+	identities, err := r.loadIdentities()
+	if err != nil {
+		return err
+	}
+
+	files := r.FilePaths
+	if len(files) == 0 {
+		if files, err = r.staleFiles(v, all); err != nil {
+			return err
+		}
+	}
+	if len(files) == 0 {
+		successColor.Println("所有文件都已加密给当前的公钥列表")
+		return nil
+	}
+
+	c := cryptor.NewKeyCryptor(v.Recipients, identities, cryptor.Options{})
+	h := handler.NewHandler(files, r.Dir, c, handler.Options{
+		Jobs:      r.Jobs,
+		Progress:  showProgress(r.NoProgress),
+		OnSuccess: func(inputPath, _ string) { v.Record(inputPath) },
+	})
+
+	err = h.HandleEach("Rekeyed", c.Reencrypt)
+	if saveErr := v.Save(); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+// Status 列出每个文件的状态, 包括加密给过期公钥列表的文件和已不存在的文件
+func (r *VaultRunner) Status() error {
+	v, err := vault.Open(r.Dir)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := v.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("recipients: %d, fingerprint: %s\n", len(v.Recipients), v.Fingerprint)
+
+	stale, missing, broken := 0, 0, 0
+	for _, s := range statuses {
+		switch s.State {
+		case vault.StateCurrent:
+			successColor.Printf("%-10s %s\n", s.State, s.Name)
+		case vault.StateOutdated:
+			stale++
+			errorColor.Printf("%-10s %s (%s)\n", s.State, s.Name, s.Reason)
+		case vault.StateUntracked:
+			stale++
+			warnColor.Printf("%-10s %s\n", s.State, s.Name)
+		case vault.StateMissing:
+			missing++
+			warnColor.Printf("%-10s %s (recipients %s)\n", s.State, s.Name, s.Entry.Recipients)
+		default:
+			broken++
+			errorColor.Printf("%-10s %s (%s)\n", s.State, s.Name, s.Reason)
+		}
+	}
+
+	if stale > 0 {
+		warnColor.Printf("%d 个文件需要处理, 运行 'siho vault rekey' 重新加密\n", stale)
+	}
+	if missing > 0 {
+		warnColor.Printf("%d 个文件已不存在, 清单中的记录会在下次 add 或 rekey 时移除\n", missing)
+	}
+	if broken > 0 {
+		errorColor.Printf("%d 个文件无法读取文件头, 可能已损坏或不是 age 加密文件\n", broken)
+	}
+	return nil
+}
+
+// staleFiles 返回需要重新加密的文件, all 为 true 时返回所有文件
+func (r *VaultRunner) staleFiles(v *vault.Vault, all bool) ([]string, error) {
+	if all {
+		return v.Files()
+	}
+
+	statuses, err := v.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, s := range statuses {
+		if s.State == vault.StateOutdated || s.State == vault.StateUntracked {
+			files = append(files, filepath.Join(v.Dir, s.Name))
+		}
+	}
+	return files, nil
+}
+
+// loadIdentities 读取私钥, 未通过参数指定时使用环境变量 SIHO_IDENTITY
+func (r *VaultRunner) loadIdentities() ([]age.Identity, error) {
+	path := r.IdentityPath
+	if path == "" {
+		path = os.Getenv("SIHO_IDENTITY")
+	}
+	if path == "" {
+		return nil, errors.New("未指定私钥文件, 请使用 --identity 或设置 SIHO_IDENTITY")
+	}
+	return cryptor.LoadIdentities(path)
+}
@@ -0,0 +1,336 @@
+package cryptor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"siho/internal/ageheader"
+	"siho/internal/atomicfile"
+	"siho/internal/handler"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Options 加密器的可选配置
+type Options struct {
+	Overwrite     bool   // 允许覆盖已存在的输出文件
+	WorkFactor    int    // 加密时的 scrypt 工作因子 (logN), 0 表示使用默认值
+	MaxWorkFactor int    // 解密时接受的最大工作因子, 防止恶意文件耗尽资源, 0 表示使用默认值
+	Armor         bool   // 输出 PEM 风格的 ASCII 装甲文本
+	Compress      string // 加密前的压缩算法: none, gzip, zstd, auto
+}
+
+// ageCryptor 基于 age 的通用加解密实现, 由具体的 Cryptor 提供 Recipients 和 Identities
+type ageCryptor struct {
+	recipients []age.Recipient
+	identities []age.Identity
+	opts       Options
+	progress   handler.ProgressFunc // 读取输入时报告进度, 可以为 nil
+}
+
+// SetProgress 设置进度回调, 需要在开始处理文件之前调用
+func (c *ageCryptor) SetProgress(fn handler.ProgressFunc) {
+	c.progress = fn
+}
+
+// openInput 打开输入文件, 设置了进度回调时统计读取的字节数
+func (c *ageCryptor) openInput(path string) (*os.File, io.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.progress == nil {
+		return f, f, nil
+	}
+	return f, &progressReader{r: f, path: path, report: c.progress}, nil
+}
+
+// progressReader 在读取时回调已读取的字节数
+type progressReader struct {
+	r      io.Reader
+	path   string
+	report handler.ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.report(p.path, int64(n))
+	}
+	return n, err
+}
+
+// Encrypt 直接使用预先创建好的 Recipients
+func (c *ageCryptor) Encrypt(inputPath, outputPath string) (err error) {
+	// 先写入同目录的临时文件, 成功后再原子地重命名, 避免留下写了一半的输出
+	outputFile, err := atomicfile.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("创建输出文件失败 '%s': %w", outputPath, err)
+	}
+	// 如果 err 不为 nil (即加密失败), 则删除临时文件, 提交成功后 Abort 不做任何事
+	defer outputFile.Abort()
+
+	if err = c.EncryptTo(outputFile, inputPath, nil); err != nil {
+		return err
+	}
+	return outputFile.Commit(c.opts.Overwrite)
+}
+
+// EncryptTo 加密文件并写入 dst, tee 不为 nil 时读取的明文同时写入 tee, 用于边加密边计算哈希
+func (c *ageCryptor) EncryptTo(dst io.Writer, inputPath string, tee io.Writer) error {
+	inputFile, input, err := c.openInput(inputPath)
+	if err != nil {
+		return fmt.Errorf("打开输入文件失败: %w", err)
+	}
+	defer inputFile.Close()
+
+	info, err := inputFile.Stat()
+	if err != nil {
+		return fmt.Errorf("读取输入文件信息失败: %w", err)
+	}
+	if tee != nil {
+		input = io.TeeReader(input, tee)
+	}
+
+	// 原始文件名、权限、修改时间和压缩算法写在明文最前面, 随内容一起加密和认证
+	meta := newMetadata(info)
+	meta.Compression, input = chooseCompression(c.opts.Compress, info.Name(), input)
+	return c.encrypt(dst, input, meta)
+}
+
+// EncryptStream 加密任意数据流, 不写入元数据, 适用于文本片段
+func (c *ageCryptor) EncryptStream(dst io.Writer, src io.Reader) error {
+	return c.encrypt(dst, src, nil)
+}
+
+// encrypt 将 src 加密写入 dst, meta 不为 nil 时写在明文最前面
+func (c *ageCryptor) encrypt(dst io.Writer, src io.Reader, meta *metadata) error {
+	// 装甲模式下, age 的二进制输出先经过 base64 编码再写入
+	var armorWriter io.WriteCloser
+	if c.opts.Armor {
+		armorWriter = armor.NewWriter(dst)
+		dst = armorWriter
+	}
+
+	// 直接复用 c.recipients, 避免重复的密钥派生计算
+	wc, err := age.Encrypt(dst, c.recipients...)
+	if err != nil {
+		return err
+	}
+
+	// 正文按元数据中记录的算法压缩, 元数据头本身不压缩
+	var body io.WriteCloser = nopWriteCloser{wc}
+	if meta != nil {
+		if err := writeMetadata(wc, meta); err != nil {
+			return err
+		}
+		if body, err = compressWriter(meta.Compression, wc); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(body, src); err != nil {
+		return fmt.Errorf("复制文件内容至加密流时出错: %w", err)
+	}
+
+	if err := body.Close(); err != nil {
+		return fmt.Errorf("压缩过程中关闭 writer 时出错: %w", err)
+	}
+
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("加密过程中关闭 writer 时出错: %w", err)
+	}
+
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return fmt.Errorf("写入装甲结尾时出错: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Decrypt 直接使用预先创建好的 Identities
+// 输出文件名由调用方事先通过 OriginalName 确定, 若密文中带有元数据, 则还原权限和修改时间
+func (c *ageCryptor) Decrypt(inputPath, outputPath string) (*handler.DecryptResult, error) {
+	inputFile, input, err := c.openInput(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开输入文件出错: %w", err)
+	}
+	defer inputFile.Close()
+
+	meta, r, err := c.open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// 先写入同目录的临时文件, 成功后再原子地重命名, 避免留下写了一半的输出
+	outputFile, err := atomicfile.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建输出文件失败 '%s': %w", outputPath, err)
+	}
+	defer outputFile.Abort()
+
+	if _, err = io.Copy(outputFile, r); err != nil {
+		return nil, fmt.Errorf("复制解密数据到输出文件时出错: %w", err)
+	}
+
+	if err = outputFile.Commit(c.opts.Overwrite); err != nil {
+		return nil, err
+	}
+
+	result := &handler.DecryptResult{OutputPath: outputPath}
+	if meta != nil {
+		meta.apply(outputPath)
+		result.Restored = true
+	}
+	return result, nil
+}
+
+// OriginalName 只解密到元数据头, 返回其中记录的原始文件名, 不读取正文
+// 密文中没有元数据或记录的名称不能安全地用作文件名时返回空字符串
+func (c *ageCryptor) OriginalName(inputPath string) (string, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("打开输入文件出错: %w", err)
+	}
+	defer f.Close()
+
+	meta, r, err := c.open(f)
+	if err != nil {
+		return "", err
+	}
+	r.Close()
+	return meta.safeName(), nil
+}
+
+// DecryptTo 将解密后的明文写入任意 writer, 不落盘
+func (c *ageCryptor) DecryptTo(inputPath string, w io.Writer) error {
+	inputFile, input, err := c.openInput(inputPath)
+	if err != nil {
+		return fmt.Errorf("打开输入文件出错: %w", err)
+	}
+	defer inputFile.Close()
+
+	return c.DecryptStream(w, input)
+}
+
+// DecryptStream 解密任意数据流, 若带有元数据头则跳过
+func (c *ageCryptor) DecryptStream(dst io.Writer, src io.Reader) error {
+	_, r, err := c.open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if _, err = io.Copy(dst, r); err != nil {
+		return fmt.Errorf("复制解密数据时出错: %w", err)
+	}
+	return nil
+}
+
+// open 解密输入流并读取元数据头, 返回元数据和明文 reader, 使用完毕后需要关闭 reader
+// 装甲格式的输入会被自动识别并解除装甲, 压缩过的正文会被自动解压
+func (c *ageCryptor) open(src io.Reader) (*metadata, io.ReadCloser, error) {
+	// 直接复用 c.identities
+	r, err := age.Decrypt(ageheader.NewReader(src), c.identities...)
+	if err != nil {
+		return nil, nil, classifyError(err)
+	}
+
+	meta, body, err := readMetadata(corruptReader{r})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	algo := ""
+	if meta != nil {
+		algo = meta.Compression
+	}
+	rc, err := decompressReader(algo, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", handler.ErrCorrupt, err)
+	}
+	return meta, struct {
+		io.Reader
+		io.Closer
+	}{corruptReader{rc}, rc}, nil
+}
+
+// CheckKey 只解开文件头中的文件密钥, 不读取正文
+// 用于批量解密前快速确认密码或私钥是否正确
+func (c *ageCryptor) CheckKey(inputPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("打开输入文件出错: %w", err)
+	}
+	defer f.Close()
+
+	_, err = age.Decrypt(ageheader.NewReader(f), c.identities...)
+	return classifyError(err)
+}
+
+// classifyError 区分密码错误、密文损坏和读取失败, 便于调用方决定退出码
+func classifyError(err error) error {
+	var noMatch *age.NoIdentityMatchError
+	var pathErr *fs.PathError
+	switch {
+	case err == nil, errors.Is(err, handler.ErrCorrupt), errors.Is(err, handler.ErrWrongPassword):
+		return err
+	case errors.As(err, &noMatch):
+		return fmt.Errorf("%w: %w", handler.ErrWrongPassword, err)
+	case errors.As(err, &pathErr):
+		return err
+	}
+	return fmt.Errorf("%w: %w", handler.ErrCorrupt, err)
+}
+
+// corruptReader 将解密正文时的读取错误 (认证失败、截断等) 标记为密文损坏
+type corruptReader struct {
+	r io.Reader
+}
+
+func (c corruptReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if err != nil && err != io.EOF {
+		err = classifyError(err)
+	}
+	return n, err
+}
+
+// Reencrypt 用当前的 Identities 解密文件, 再原样加密给当前的 Recipients, 原子地替换原文件
+// 明文中的元数据头被原样保留
+func (c *ageCryptor) Reencrypt(path string) (err error) {
+	inputFile, input, err := c.openInput(path)
+	if err != nil {
+		return fmt.Errorf("打开文件出错: %w", err)
+	}
+	defer inputFile.Close()
+
+	info, err := inputFile.Stat()
+	if err != nil {
+		return fmt.Errorf("读取文件信息失败: %w", err)
+	}
+
+	r, err := age.Decrypt(ageheader.NewReader(input), c.identities...)
+	if err != nil {
+		return classifyError(err)
+	}
+
+	outputFile, err := atomicfile.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败 '%s': %w", path, err)
+	}
+	defer outputFile.Abort()
+
+	if err = c.encrypt(outputFile, corruptReader{r}, nil); err != nil {
+		return err
+	}
+	outputFile.Chmod(info.Mode().Perm())
+
+	return outputFile.Commit(true)
+}
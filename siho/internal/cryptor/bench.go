@@ -0,0 +1,59 @@
+package cryptor
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt 参数与 age 保持一致: r=8, p=1, 派生 32 字节密钥
+const (
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// ErrNotEnoughMemory scrypt 需要的内存超过了当前可用的内存
+var ErrNotEnoughMemory = errors.New("可用内存不足")
+
+// ScryptMemory 返回给定工作因子下 scrypt 需要的内存 (字节)
+func ScryptMemory(logN int) int64 {
+	return 128 * scryptR * (int64(1) << logN)
+}
+
+// CheckScryptMemory 检查当前可用的内存是否足够以给定工作因子派生密钥
+// 内存不足时派生会让系统开始换页甚至触发 OOM, 在开始之前就拒绝; 无法得知可用内存的平台上不做检查
+func CheckScryptMemory(logN int) error {
+	available, ok := availableMemory()
+	if !ok {
+		return nil
+	}
+	if need := ScryptMemory(logN); need > available {
+		return fmt.Errorf("%w: scrypt 工作因子 %d 需要 %d MiB 内存, 当前可用 %d MiB", ErrNotEnoughMemory, logN, need>>20, available>>20)
+	}
+	return nil
+}
+
+// BenchmarkScrypt 测量在当前机器上以给定工作因子派生一次密钥的耗时
+func BenchmarkScrypt(logN int) (time.Duration, error) {
+	if err := ValidateWorkFactor(logN); err != nil {
+		return 0, err
+	}
+	if err := CheckScryptMemory(logN); err != nil {
+		return 0, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, fmt.Errorf("生成随机盐失败: %w", err)
+	}
+
+	start := time.Now()
+	if _, err := scrypt.Key([]byte("siho-bench"), salt, 1<<logN, scryptR, scryptP, scryptKeyLen); err != nil {
+		return 0, fmt.Errorf("scrypt 密钥派生失败: %w", err)
+	}
+	return time.Since(start), nil
+}
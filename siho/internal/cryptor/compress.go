@@ -0,0 +1,137 @@
+package cryptor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩算法, 记录在元数据头中, 解密时据此自动解压
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+	CompressAuto = "auto" // 对已经压缩过的输入不再压缩, 其余使用 zstd
+)
+
+// ValidateCompression 检查压缩算法名称
+func ValidateCompression(name string) error {
+	switch name {
+	case "", CompressNone, CompressGzip, CompressZstd, CompressAuto:
+		return nil
+	}
+	return fmt.Errorf("不支持的压缩算法: %s (可选 none, gzip, zstd, auto)", name)
+}
+
+// compressedMagics 常见压缩格式和媒体格式的文件头
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b},                       // gzip
+	{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+	[]byte("BZh"),                      // bzip2
+	{0x04, 0x22, 0x4d, 0x18},           // lz4
+	[]byte("PK\x03\x04"),               // zip, docx, jar 等
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	[]byte("Rar!"),                     // rar
+	{0x89, 'P', 'N', 'G'},              // png
+	{0xff, 0xd8, 0xff},                 // jpeg
+	[]byte("GIF8"),                     // gif
+	{0x1a, 0x45, 0xdf, 0xa3},           // mkv, webm
+	[]byte("OggS"),                     // ogg
+	[]byte("fLaC"),                     // flac
+	[]byte("ID3"),                      // mp3
+	[]byte("%PDF"),                     // pdf (内部流通常已压缩)
+	[]byte("age-encryption.org/"),      // age 密文
+	[]byte("-----BEGIN AGE ENCRYPTED"), // 装甲格式的 age 密文
+}
+
+// compressedExts 魔数不够可靠时, 按扩展名判断的已压缩格式
+var compressedExts = []string{
+	".gz", ".tgz", ".zst", ".xz", ".bz2", ".lz4", ".zip", ".7z", ".rar",
+	".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic", ".avif",
+	".mp4", ".mov", ".mkv", ".webm", ".mp3", ".m4a", ".aac", ".ogg", ".opus", ".flac",
+}
+
+// looksCompressed 根据文件头和扩展名判断输入是否已经压缩
+func looksCompressed(name string, head []byte) bool {
+	if slices.Contains(compressedExts, strings.ToLower(filepath.Ext(name))) {
+		return true
+	}
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+	// RIFF 容器中的 webp, mp4/mov 的 ftyp box
+	if len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP" {
+		return true
+	}
+	if len(head) >= 8 && string(head[4:8]) == "ftyp" {
+		return true
+	}
+	return false
+}
+
+// chooseCompression 解析 auto 模式, 返回实际使用的算法和可继续读取完整内容的 reader
+func chooseCompression(mode, name string, src io.Reader) (string, io.Reader) {
+	switch mode {
+	case "", CompressNone:
+		return "", src
+	case CompressAuto:
+		br := bufio.NewReader(src)
+		head, _ := br.Peek(32)
+		if looksCompressed(name, head) {
+			return "", br
+		}
+		return CompressZstd, br
+	default:
+		return mode, src
+	}
+}
+
+// compressWriter 按算法包装 writer, 算法为空时原样返回
+func compressWriter(algo string, w io.Writer) (io.WriteCloser, error) {
+	switch algo {
+	case "":
+		return nopWriteCloser{w}, nil
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("不支持的压缩算法: %s", algo)
+}
+
+// decompressReader 按算法包装 reader, 返回的 ReadCloser 需要关闭以释放解压器
+func decompressReader(algo string, r io.Reader) (io.ReadCloser, error) {
+	switch algo {
+	case "":
+		return io.NopCloser(r), nil
+	case CompressGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("初始化 gzip 解压失败: %w", err)
+		}
+		return zr, nil
+	case CompressZstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("初始化 zstd 解压失败: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("密文使用了不支持的压缩算法: %s", algo)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
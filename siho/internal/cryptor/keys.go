@@ -0,0 +1,105 @@
+package cryptor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+)
+
+// KeyCryptor 使用公钥加密给多个接收者, 并用私钥解密
+type KeyCryptor struct {
+	ageCryptor
+}
+
+// NewKeyCryptor 创建基于公钥的加密器, recipients 用于加密, identities 用于解密, 二者可以只提供其一
+func NewKeyCryptor(recipients []age.Recipient, identities []age.Identity, opts Options) *KeyCryptor {
+	return &KeyCryptor{ageCryptor{
+		recipients: recipients,
+		identities: identities,
+		opts:       opts,
+	}}
+}
+
+// NewIdentityCryptor 使用单个 X25519 私钥创建加密器, 加密给该私钥对应的公钥并用它解密
+// 与密码加密不同, 每个文件都不需要再派生密钥, 适合大量文件共用同一个随机密钥的场景
+func NewIdentityCryptor(identity string, opts Options) (*KeyCryptor, error) {
+	id, err := age.ParseX25519Identity(identity)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return NewKeyCryptor([]age.Recipient{id.Recipient()}, []age.Identity{id}, opts), nil
+}
+
+// ParseRecipient 解析单个公钥, 支持 age1... 和 ssh-ed25519/ssh-rsa
+func ParseRecipient(s string) (age.Recipient, error) {
+	if strings.HasPrefix(s, "ssh-") {
+		return agessh.ParseRecipient(s)
+	}
+
+	recipients, err := age.ParseRecipients(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) != 1 {
+		return nil, fmt.Errorf("无法解析公钥: %s", s)
+	}
+	return recipients[0], nil
+}
+
+// ReadRecipientLines 读取公钥列表, 忽略空行和以 # 开头的注释, 返回去除首尾空白后的每一行
+func ReadRecipientLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取公钥列表失败: %w", err)
+	}
+	return lines, nil
+}
+
+// LoadIdentities 从文件读取私钥, 支持 age 私钥文件和 SSH 私钥
+func LoadIdentities(path string) ([]age.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		id, err := agessh.ParseIdentity(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析 SSH 私钥失败: %w", err)
+		}
+		return []age.Identity{id}, nil
+	}
+
+	ids, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥文件失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("私钥文件中没有私钥")
+	}
+	return ids, nil
+}
+
+// GenerateIdentity 生成新的 X25519 私钥, 返回私钥和对应的公钥字符串
+func GenerateIdentity() (identity, recipient string, err error) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", "", fmt.Errorf("生成私钥失败: %w", err)
+	}
+	return id.String(), id.Recipient().String(), nil
+}
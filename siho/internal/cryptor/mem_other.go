@@ -0,0 +1,8 @@
+//go:build !linux
+
+package cryptor
+
+// availableMemory 当前平台无法得知可用内存
+func availableMemory() (int64, bool) {
+	return 0, false
+}
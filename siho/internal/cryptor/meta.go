@@ -0,0 +1,111 @@
+package cryptor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// metaMagic 元数据头的标识, 写在 age 加密流的最前面, 因此同样受 age 的认证保护
+const metaMagic = "siho-meta/v1\n"
+
+// maxMetaSize 元数据头的最大长度, 防止损坏的数据导致大量内存分配
+const maxMetaSize = 64 << 10
+
+// metadata 原始文件的基本信息
+type metadata struct {
+	Name    string    `json:"name"`  // 原始文件名 (不含目录)
+	Mode    uint32    `json:"mode"`  // 权限位
+	ModTime time.Time `json:"mtime"` // 修改时间
+
+	Compression string `json:"compression,omitempty"` // 正文的压缩算法, 为空表示未压缩
+}
+
+// newMetadata 根据原始文件信息构建元数据
+func newMetadata(info os.FileInfo) *metadata {
+	return &metadata{
+		Name:    info.Name(),
+		Mode:    uint32(info.Mode().Perm()),
+		ModTime: info.ModTime(),
+	}
+}
+
+// writeMetadata 写入元数据头: 标识 + 4 字节长度 + JSON
+func writeMetadata(w io.Writer, m *metadata) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("序列化元数据失败: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(metaMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("写入元数据失败: %w", err)
+	}
+	return nil
+}
+
+// readMetadata 尝试读取元数据头, 返回元数据和指向正文的 reader
+// 旧版本生成的密文没有元数据头, 此时返回 nil 和完整的正文
+func readMetadata(r io.Reader) (*metadata, io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(metaMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("读取元数据失败: %w", err)
+	}
+	if string(magic) != metaMagic {
+		return nil, br, nil
+	}
+	br.Discard(len(metaMagic))
+
+	var size uint32
+	if err := binary.Read(br, binary.BigEndian, &size); err != nil {
+		return nil, nil, fmt.Errorf("读取元数据长度失败: %w", err)
+	}
+	if size > maxMetaSize {
+		return nil, nil, fmt.Errorf("元数据过大: %d 字节", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return nil, nil, fmt.Errorf("读取元数据失败: %w", err)
+	}
+
+	var m metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("解析元数据失败: %w", err)
+	}
+	return &m, br, nil
+}
+
+// safeName 返回可安全用作输出文件名的原始名称, 不合法时返回空字符串
+func (m *metadata) safeName() string {
+	if m == nil || m.Name == "" || m.Name == "." || m.Name == ".." {
+		return ""
+	}
+	if filepath.Base(m.Name) != m.Name || filepath.IsAbs(m.Name) {
+		return ""
+	}
+	return m.Name
+}
+
+// apply 尽力还原权限和修改时间
+func (m *metadata) apply(path string) {
+	if m.Mode != 0 {
+		os.Chmod(path, os.FileMode(m.Mode).Perm())
+	}
+	if !m.ModTime.IsZero() {
+		os.Chtimes(path, m.ModTime, m.ModTime)
+	}
+}
@@ -0,0 +1,193 @@
+package cryptor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	mtime := time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		meta metadata
+	}{
+		{"plain", metadata{Name: "report.pdf", Mode: 0644, ModTime: mtime}},
+		{"compressed", metadata{Name: "notes.txt", Mode: 0600, ModTime: mtime, Compression: CompressZstd}},
+		{"unicode name", metadata{Name: "旅行 照片.jpg", Mode: 0640, ModTime: mtime}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeMetadata(&buf, &tt.meta); err != nil {
+				t.Fatal(err)
+			}
+			buf.WriteString("body")
+
+			got, body, err := readMetadata(&buf)
+			if err != nil {
+				t.Fatalf("readMetadata: %v", err)
+			}
+			if got == nil || got.Name != tt.meta.Name || got.Mode != tt.meta.Mode ||
+				!got.ModTime.Equal(tt.meta.ModTime) || got.Compression != tt.meta.Compression {
+				t.Errorf("readMetadata = %+v, want %+v", got, tt.meta)
+			}
+			if rest, _ := io.ReadAll(body); string(rest) != "body" {
+				t.Errorf("body after metadata = %q, want %q", rest, "body")
+			}
+		})
+	}
+}
+
+func TestReadMetadataWithoutHeader(t *testing.T) {
+	// 旧版本生成的密文没有元数据头, 正文原样返回
+	tests := []string{"", "hello", "siho-meta", "siho-meta/v2\nxxxx"}
+
+	for _, data := range tests {
+		meta, body, err := readMetadata(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("readMetadata(%q): %v", data, err)
+		}
+		if meta != nil {
+			t.Errorf("readMetadata(%q) = %+v, want nil", data, meta)
+		}
+		if rest, _ := io.ReadAll(body); string(rest) != data {
+			t.Errorf("readMetadata(%q) body = %q", data, rest)
+		}
+	}
+}
+
+func TestReadMetadataErrors(t *testing.T) {
+	header := func(size uint32, data string) string {
+		var buf bytes.Buffer
+		buf.WriteString(metaMagic)
+		binary.Write(&buf, binary.BigEndian, size)
+		buf.WriteString(data)
+		return buf.String()
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"missing length", metaMagic + "\x00", "读取元数据长度失败"},
+		{"too large", header(maxMetaSize+1, ""), "元数据过大"},
+		{"truncated", header(10, "{}"), "读取元数据失败"},
+		{"invalid json", header(3, "{x}"), "解析元数据失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readMetadata(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readMetadata error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{".bashrc", ".bashrc"},
+		{"", ""},
+		{".", ""},
+		{"..", ""},
+		{"../escape.txt", ""},
+		{"dir/file.txt", ""},
+		{"/etc/passwd", ""},
+	}
+
+	for _, tt := range tests {
+		m := &metadata{Name: tt.name}
+		if got := m.safeName(); got != tt.want {
+			t.Errorf("safeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var nilMeta *metadata
+	if got := nilMeta.safeName(); got != "" {
+		t.Errorf("safeName of nil metadata = %q, want empty", got)
+	}
+}
+
+func TestDecryptRestoresMetadata(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "original.txt")
+	if err := os.WriteFile(input, []byte("hello"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC)
+	if err := os.Chtimes(input, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewPasswordCryptor("password", Options{WorkFactor: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compress := range []string{CompressNone, CompressGzip, CompressZstd} {
+		t.Run(compress, func(t *testing.T) {
+			c.opts.Compress = compress
+			encrypted := filepath.Join(dir, "random_enc")
+			if err := c.Encrypt(input, encrypted); err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			defer os.Remove(encrypted)
+
+			// 规划阶段只读取元数据头就能得到原始文件名
+			name, err := c.OriginalName(encrypted)
+			if err != nil {
+				t.Fatalf("OriginalName: %v", err)
+			}
+			if name != "original.txt" {
+				t.Errorf("OriginalName = %q, want %q", name, "original.txt")
+			}
+
+			output := filepath.Join(t.TempDir(), name)
+			result, err := c.Decrypt(encrypted, output)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if !result.Restored || result.OutputPath != output {
+				t.Errorf("Decrypt result = %+v, want restored output at %s", result, output)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "hello" {
+				t.Errorf("decrypted content = %q, want %q", data, "hello")
+			}
+			info, err := os.Stat(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0640 {
+				t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+			}
+			if !info.ModTime().Equal(mtime) {
+				t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+			}
+
+			// 输出已存在时不覆盖
+			if _, err := c.Decrypt(encrypted, output); !errors.Is(err, os.ErrExist) {
+				t.Errorf("Decrypt onto an existing file: err = %v, want os.ErrExist", err)
+			}
+		})
+	}
+}
@@ -1,105 +1,94 @@
 package cryptor
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
-	"io"
-	"os"
+	"sync"
 
 	"filippo.io/age"
 )
 
+const (
+	DefaultWorkFactor    = 18 // age 的默认值, 在现代机器上约 1 秒
+	DefaultMaxWorkFactor = 22 // age 的默认上限, 在现代机器上约 15 秒
+	MinWorkFactor        = 1  // age 允许的最小工作因子
+	MaxWorkFactor        = 30 // age 允许的最大工作因子
+)
+
+// ValidateWorkFactor 检查工作因子是否在 age 允许的范围内
+func ValidateWorkFactor(logN int) error {
+	if logN < MinWorkFactor || logN > MaxWorkFactor {
+		return fmt.Errorf("scrypt 工作因子必须在 %d 到 %d 之间: %d", MinWorkFactor, MaxWorkFactor, logN)
+	}
+	return nil
+}
+
 // PasswordCryptor 结构体中缓存可复用的 Recipient 和 Identity
 type PasswordCryptor struct {
-	recipient age.Recipient
-	identity  age.Identity
+	ageCryptor
 }
 
 // NewPasswordCryptor 在构造时就生成 Recipient 和 Identity, 并处理可能发生的错误
-func NewPasswordCryptor(p string) (*PasswordCryptor, error) {
+func NewPasswordCryptor(p string, opts Options) (*PasswordCryptor, error) {
 	recipient, err := age.NewScryptRecipient(p)
 	if err != nil {
 		return nil, fmt.Errorf("创建 age recipient 失败: %w", err)
 	}
+	if opts.WorkFactor != 0 {
+		if err := ValidateWorkFactor(opts.WorkFactor); err != nil {
+			return nil, err
+		}
+		recipient.SetWorkFactor(opts.WorkFactor)
+	}
 
 	identity, err := age.NewScryptIdentity(p)
 	if err != nil {
 		return nil, fmt.Errorf("创建 age identity 失败: %w", err)
 	}
-
-	return &PasswordCryptor{
-		recipient: recipient,
-		identity:  identity,
-	}, nil
-}
-
-// Encrypt 直接使用预先创建好的 Recipient
-func (c *PasswordCryptor) Encrypt(inputPath, outputPath string) (err error) {
-	inputFile, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("打开输入文件失败: %w", err)
-	}
-	defer inputFile.Close()
-
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("创建输出文件失败 '%s': %w", outputPath, err)
-	}
-	// defer 配合具名返回值 err, 确保在函数退出时执行清理逻辑
-	// 如果 err 不为 nil (即加密失败), 则在关闭文件后删除已创建的输出文件
-	defer func() {
-		outputFile.Close()
-		if err != nil {
-			os.Remove(outputPath)
+	if opts.MaxWorkFactor != 0 {
+		if err := ValidateWorkFactor(opts.MaxWorkFactor); err != nil {
+			return nil, err
 		}
-	}()
-
-	// 直接复用 c.recipient, 避免重复的密钥派生计算
-	wc, err := age.Encrypt(outputFile, c.recipient)
-	if err != nil {
-		return err
-	}
-
-	if _, err = io.Copy(wc, inputFile); err != nil {
-		return fmt.Errorf("复制文件内容至加密流时出错: %w", err)
+		identity.SetMaxWorkFactor(opts.MaxWorkFactor)
 	}
 
-	if err = wc.Close(); err != nil {
-		return fmt.Errorf("加密过程中关闭 writer 时出错: %w", err)
-	}
+	return &PasswordCryptor{ageCryptor{
+		recipients: []age.Recipient{recipient},
+		identities: []age.Identity{&cachedIdentity{Identity: identity, keys: make(map[string][]byte)}},
+		opts:       opts,
+	}}, nil
+}
 
-	return nil
+// cachedIdentity 记住已经解开的文件密钥
+// 解密前规划输出路径时要先读取每个文件的元数据头, 缓存后正式解密时不必为同一个文件再派生一次密码
+type cachedIdentity struct {
+	age.Identity
+	mu   sync.Mutex
+	keys map[string][]byte // 文件头中 stanza 的摘要 -> 文件密钥
 }
 
-// Decrypt 直接使用预先创建好的 Identity
-func (c *PasswordCryptor) Decrypt(inputPath, outputPath string) (err error) {
-	inputFile, err := os.Open(inputPath)
+func (c *cachedIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	data, err := json.Marshal(stanzas)
 	if err != nil {
-		return fmt.Errorf("打开输入文件出错: %w", err)
+		return c.Identity.Unwrap(stanzas)
 	}
-	defer inputFile.Close()
-
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("创建输出文件失败 '%s': %w", outputPath, err)
+	sum := sha256.Sum256(data)
+	id := string(sum[:])
+
+	c.mu.Lock()
+	key, ok := c.keys[id]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
 	}
-	// defer 配合具名返回值 err, 确保在函数退出时执行清理逻辑
-	// 如果 err 不为 nil (即解密失败), 则在关闭文件后删除已创建的输出文件
-	defer func() {
-		outputFile.Close()
-		if err != nil {
-			os.Remove(outputPath)
-		}
-	}()
 
-	// 直接复用 c.identity
-	r, err := age.Decrypt(inputFile, c.identity)
+	key, err = c.Identity.Unwrap(stanzas)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	if _, err = io.Copy(outputFile, r); err != nil {
-		return fmt.Errorf("复制解密数据到输出文件时出错: %w", err)
-	}
-
-	return nil
+	c.mu.Lock()
+	c.keys[id] = key
+	c.mu.Unlock()
+	return key, nil
 }
@@ -1,13 +1,21 @@
 package handler
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"siho/internal/atomicfile"
+	"siho/internal/progress"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -20,143 +28,379 @@ var (
 
 type Cryptor interface {
 	Encrypt(inputPath, outputPath string) error
-	Decrypt(inputPath, outputPath string) error
+	Decrypt(inputPath, outputPath string) (*DecryptResult, error)
+	DecryptTo(inputPath string, w io.Writer) error
+	SetProgress(fn ProgressFunc)
+}
+
+// ProgressFunc 报告某个输入文件新读取的字节数, 会被多个 worker 并发调用
+type ProgressFunc func(inputPath string, n int64)
+
+// DecryptResult 解密的结果
+type DecryptResult struct {
+	OutputPath string // 实际写入的路径
+	Restored   bool   // 是否已根据密文中的元数据还原权限和修改时间
+}
+
+// Options 处理器的可选配置
+type Options struct {
+	Force   bool // 允许覆盖已存在的输出文件
+	InPlace bool // 原地处理: 输出写到输入文件旁边, 成功后删除输入文件
+	Shred   bool // 原地加密时, 删除前先用随机数据覆盖明文
+
+	ObfuscateNames bool // 加密时使用随机的输出文件名
+
+	Jobs         int  // 并发 worker 数量, 0 表示使用 CPU 核数
+	Progress     bool // 显示每个文件的字节进度条和整体吞吐量
+	SkipExisting bool // 跳过输出已存在的文件, 用于中断后继续处理
+
+	Selection Selection // 输入文件的挑选规则
+
+	JSON bool // 以 JSON Lines 格式在标准输出报告每个文件的结果, 其他提示改写到标准错误
+
+	// OnSuccess 在每个文件成功处理后调用, 只在收集结果的协程中调用, 无需加锁
+	OnSuccess func(inputPath, outputPath string)
 }
 
 type Handler struct {
 	FilePaths []string
 	OutputDir string
 	crypt     Cryptor
+	opts      Options
+
+	annotate func(rec *Record) // 输出 JSON 记录前补充操作特有的字段, 可以为 nil
 }
 
-func NewHandler(paths []string, outputDir string, c Cryptor) *Handler {
+func NewHandler(paths []string, outputDir string, c Cryptor, opts Options) *Handler {
 	return &Handler{
 		FilePaths: paths,
 		OutputDir: outputDir,
 		crypt:     c,
+		opts:      opts,
 	}
 }
 
 // HandleEncrypt 统一处理文件和目录的加密逻辑
 func (h *Handler) HandleEncrypt() error {
-	// 定义加密文件的具体操作
-	encryptFile := func(inputPath string) (string, error) {
-		baseName := filepath.Base(inputPath)
-		outputPath := filepath.Join(h.OutputDir, fmt.Sprintf("%s_enc", baseName))
-		err := h.crypt.Encrypt(inputPath, outputPath)
-		return outputPath, err
+	if h.opts.InPlace {
+		if h.opts.Shred {
+			h.notice("%s\n", ShredWarning)
+		}
+		return h.processFiles("Encrypted", h.encryptedPath, h.encryptInPlace)
 	}
-
-	return h.processFiles("Encrypted", encryptFile)
+	return h.processFiles("Encrypted", h.encryptedPath, h.encrypt)
 }
 
 // HandleDecrypt 统一处理文件和目录的解密逻辑
 func (h *Handler) HandleDecrypt() error {
-	// 定义解密文件的具体操作
-	decryptFile := func(inputPath string) (string, error) {
-		baseName := filepath.Base(inputPath)
-		var outputBaseName string
-		// 根据文件名是否以 "_enc" 结尾, 决定输出文件名
-		if strings.HasSuffix(baseName, "_enc") {
-			outputBaseName = strings.TrimSuffix(baseName, "_enc")
-		} else {
-			outputBaseName = fmt.Sprintf("%s_dec", baseName)
-		}
-		outputPath := filepath.Join(h.OutputDir, outputBaseName)
-		err := h.crypt.Decrypt(inputPath, outputPath)
+	if h.opts.InPlace {
+		return h.processFiles("Decrypted", h.decryptedPath, h.decryptInPlace)
+	}
+	return h.processFiles("Decrypted", h.decryptedPath, h.decrypt)
+}
+
+// HandleEach 通过 worker pool 对每个文件执行 fn, 不产生新的输出文件
+func (h *Handler) HandleEach(opName string, fn func(inputPath string) error) error {
+	return h.HandleEachOutput(opName, func(inputPath string) (string, error) {
+		return inputPath, fn(inputPath)
+	})
+}
+
+// HandleEachOutput 与 HandleEach 相同, fn 返回的路径会作为结果报告
+func (h *Handler) HandleEachOutput(opName string, fn func(inputPath string) (string, error)) error {
+	each := func(inputPath, _ string) (string, error) {
+		return fn(inputPath)
+	}
+	return h.processFiles(opName, nil, each)
+}
+
+// encrypt 加密单个文件, 返回输出路径
+func (h *Handler) encrypt(inputPath, outputPath string) (string, error) {
+	return outputPath, h.crypt.Encrypt(inputPath, outputPath)
+}
+
+// decrypt 解密单个文件, 返回实际的输出路径
+func (h *Handler) decrypt(inputPath, outputPath string) (string, error) {
+	result, err := h.crypt.Decrypt(inputPath, outputPath)
+	if err != nil {
 		return outputPath, err
 	}
-	return h.processFiles("Decrypted", decryptFile)
+	return result.OutputPath, nil
+}
+
+// outputDirFor 返回输出目录, 原地模式下为输入文件所在目录
+func (h *Handler) outputDirFor(inputPath string) string {
+	if h.opts.InPlace {
+		return filepath.Dir(inputPath)
+	}
+	return h.OutputDir
+}
+
+// encryptedPath 计算加密文件的输出路径
+func (h *Handler) encryptedPath(inputPath string) string {
+	baseName := filepath.Base(inputPath)
+	// 原始文件名已保存在密文内部, 解密时可以还原
+	if h.opts.ObfuscateNames {
+		baseName = randomName()
+	}
+	return filepath.Join(h.outputDirFor(inputPath), fmt.Sprintf("%s_enc", baseName))
+}
+
+// decryptedPath 计算解密文件的输出路径, 密文中记录了原始文件名时使用原始文件名
+func (h *Handler) decryptedPath(inputPath string) string {
+	name := DecryptedName(filepath.Base(inputPath))
+	if r, ok := h.crypt.(NameReader); ok {
+		// 读取失败 (如密文损坏) 时沿用默认名称, 处理这个文件时再报告错误
+		if original, err := r.OriginalName(inputPath); err == nil && original != "" {
+			name = original
+		}
+	}
+	return filepath.Join(h.outputDirFor(inputPath), name)
+}
+
+// DecryptedName 根据密文文件名计算解密后的文件名
+func DecryptedName(baseName string) string {
+	// 根据文件名是否以 "_enc" 结尾, 决定输出文件名
+	if strings.HasSuffix(baseName, "_enc") {
+		return strings.TrimSuffix(baseName, "_enc")
+	}
+	return fmt.Sprintf("%s_dec", baseName)
+}
+
+// keyCheckedOps 输入均为密文, 开始前需要确认密码的操作
+var keyCheckedOps = map[string]bool{"Decrypted": true, "Verified": true, "Rekeyed": true}
+
+// job 描述一个待处理的文件及其输出路径
+type job struct {
+	inputPath  string
+	outputPath string
+}
+
+// randomName 生成随机的十六进制文件名
+func randomName() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// planJobs 为每个输入文件计算输出路径, 并在开始处理前检查冲突
+func (h *Handler) planJobs(opName string, files []string, outputPathFor func(string) string) ([]job, error) {
+	jobs := make([]job, 0, len(files))
+	owners := make(map[string]string, len(files)) // 输出路径 -> 输入路径
+
+	for _, inputPath := range files {
+		outputPath := outputPathFor(inputPath)
+		key := filepath.Clean(outputPath)
+
+		// 不同目录下的同名文件会映射到同一个输出路径
+		if prev, ok := owners[key]; ok {
+			return nil, fmt.Errorf("输出路径冲突: '%s' 与 '%s' 都将写入 %s", prev, inputPath, outputPath)
+		}
+		owners[key] = inputPath
+
+		// 输出路径指向输入文件本身时, 写入会覆盖输入, 原地模式随后还会删除它
+		if SameFile(outputPath, inputPath) {
+			return nil, fmt.Errorf("输出文件与输入文件相同: %s", inputPath)
+		}
+
+		if !h.opts.Force || h.opts.SkipExisting {
+			if _, err := os.Lstat(outputPath); err == nil {
+				// 上次中断前已完成的文件, 继续处理时直接跳过
+				if h.opts.SkipExisting {
+					if h.opts.JSON {
+						writeRecord(Record{Input: inputPath, Output: outputPath, Operation: strings.ToLower(opName), Status: StatusSkipped})
+					} else {
+						warnColor.Printf("Skipped -> %s\n", outputPath)
+					}
+					continue
+				}
+				return nil, fmt.Errorf("输出文件已存在: %s (使用 --force 覆盖)", outputPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("无法检查输出路径 %s: %w", outputPath, err)
+			}
+		}
+
+		jobs = append(jobs, job{inputPath: inputPath, outputPath: outputPath})
+	}
+
+	return jobs, nil
 }
 
 // processFiles 使用 worker pool 并发处理文件
-func (h *Handler) processFiles(opName string, processFunc func(string) (string, error)) error {
+// outputPathFor 为 nil 时表示该操作不产生输出文件, 跳过输出路径的规划
+func (h *Handler) processFiles(opName string, outputPathFor func(string) string, processFunc func(string, string) (string, error)) error {
 	// jobResult 用于在 goroutine 之间传递处理结果
 	type jobResult struct {
 		inputPath  string
 		outputPath string
+		size       int64
+		elapsed    time.Duration
 		err        error
 	}
 
-	files, err := collectFilesToProcess(h.FilePaths)
+	files, err := selectFiles(h.FilePaths, h.opts.Selection)
 	if err != nil {
 		return fmt.Errorf("无法获取待%s的文件: %w", opName, err)
 	}
 	if len(files) == 0 {
-		warnColor.Printf("未找到可%s的文件\n", opName)
+		h.notice("未找到可%s的文件\n", opName)
+		return nil
+	}
+
+	// 批量解密前先用第一个文件确认密码, 避免对每个文件重复报告同一个错误
+	if checker, ok := h.crypt.(KeyChecker); ok && keyCheckedOps[opName] {
+		if err := checker.CheckKey(files[0]); errors.Is(err, ErrWrongPassword) {
+			return fmt.Errorf("%s: %w", files[0], err)
+		}
+	}
+
+	var plan []job
+	if outputPathFor == nil {
+		for _, inputPath := range files {
+			plan = append(plan, job{inputPath: inputPath})
+		}
+	} else {
+		// 解密时读取每个密文中记录的原始文件名, 按还原后的名称检查冲突
+		plan, err = h.planJobs(opName, files, outputPathFor)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(plan) == 0 {
+		h.notice("没有需要%s的文件\n", opName)
 		return nil
 	}
 
-	// 1. 设置 worker pool
-	numWorkers := runtime.NumCPU()
-	jobs := make(chan string, numWorkers*2)
+	// 1. 设置进度跟踪, 中断时清理未完成的临时文件
+	tracker := progress.NewTracker(os.Stderr, len(plan), h.opts.Progress)
+	if h.crypt != nil {
+		h.crypt.SetProgress(tracker.Add)
+	}
+	tracker.Run()
+	stopSignals := abortOnSignal(tracker)
+	defer stopSignals()
+
+	// 2. 设置 worker pool
+	numWorkers := h.opts.Jobs
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+	numWorkers = min(numWorkers, len(plan))
+	jobs := make(chan job, numWorkers*2)
 	results := make(chan jobResult)
 	var wg sync.WaitGroup
 
-	// 2. 启动 workers
+	// 3. 启动 workers
 	wg.Add(numWorkers)
 	for range numWorkers {
 		go func() {
 			defer wg.Done()
 			// 从 jobs 通道接收任务, 直到通道关闭
-			for inputPath := range jobs {
-				outputPath, err := processFunc(inputPath)
-				results <- jobResult{inputPath: inputPath, outputPath: outputPath, err: err}
+			for j := range jobs {
+				var size int64
+				if info, err := os.Stat(j.inputPath); err == nil {
+					size = info.Size()
+				}
+				tracker.Start(j.inputPath, size)
+				begin := time.Now()
+				outputPath, err := processFunc(j.inputPath, j.outputPath)
+				tracker.Finish(j.inputPath)
+				results <- jobResult{
+					inputPath:  j.inputPath,
+					outputPath: outputPath,
+					size:       size,
+					elapsed:    time.Since(begin),
+					err:        err,
+				}
 			}
 		}()
 	}
 
-	// 3. 等待所有 worker 完成任务后关闭 results 通道
+	// 4. 等待所有 worker 完成任务后关闭 results 通道
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	// 4. 分发任务
+	// 5. 分发任务
 	go func() {
 		defer close(jobs)
-		for _, inputPath := range files {
-			jobs <- inputPath
+		for _, j := range plan {
+			jobs <- j
 		}
 	}()
 
-	// 5. 收集并处理结果
+	// 6. 收集并处理结果, 通过 tracker 输出以免与进度条互相覆盖
 	var errs []error
+	succeeded := 0
 	for result := range results {
+		if h.opts.JSON {
+			rec := newRecord(opName, result.inputPath, result.outputPath, result.size, result.elapsed, result.err)
+			if h.annotate != nil {
+				h.annotate(&rec)
+			}
+			tracker.Log(func() { writeRecord(rec) })
+		}
+
 		if result.err != nil {
 			errs = append(errs, fmt.Errorf("文件%s失败: %s, 错误: %w", opName, filepath.Base(result.inputPath), result.err))
-			errorColor.Printf("Failed -> %s\n", result.inputPath)
+			if !h.opts.JSON {
+				tracker.Log(func() { errorColor.Printf("Failed -> %s\n", result.inputPath) })
+			}
 			continue
 		}
-		successColor.Printf("%s -> %s\n", opName, result.outputPath)
+		succeeded++
+		if !h.opts.JSON {
+			tracker.Log(func() { successColor.Printf("%s -> %s\n", opName, result.outputPath) })
+		}
+		if h.opts.OnSuccess != nil {
+			h.opts.OnSuccess(result.inputPath, result.outputPath)
+		}
+	}
+	tracker.Stop()
+
+	if h.opts.Progress {
+		total, rate := tracker.Summary()
+		warnColor.Fprintf(os.Stderr, "共处理 %s, 平均 %s/s\n", progress.FormatBytes(total), progress.FormatBytes(int64(rate)))
 	}
 
 	if len(errs) > 0 {
-		return errors.Join(errs...)
+		return &BatchError{Succeeded: succeeded, Errs: errs}
 	}
 
 	return nil
 }
 
-// collectFilesToProcess 收集待处理的文件路径切片
-func collectFilesToProcess(paths []string) ([]string, error) {
-	var files []string
+// notice 输出提示信息, JSON 模式下写到标准错误以免混入结果
+func (h *Handler) notice(format string, args ...any) {
+	if h.opts.JSON {
+		warnColor.Fprintf(os.Stderr, format, args...)
+		return
+	}
+	warnColor.Printf(format, args...)
+}
 
-	for _, path := range paths {
-		info, err := os.Stat(path)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				return nil, fmt.Errorf("路径不存在: %s", path)
-			}
-			return nil, fmt.Errorf("无法访问路径 %s: %w", path, err)
-		}
+// abortOnSignal 收到中断信号时放弃所有未提交的临时文件并退出, 返回取消监听的函数
+func abortOnSignal(tracker *progress.Tracker) func() {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 
-		if info.IsDir() {
-			warnColor.Printf("Skipping directory: %s\n", path)
-			continue
+	go func() {
+		select {
+		case <-sigs:
+			tracker.Log(func() {
+				atomicfile.AbortAll()
+				errorColor.Fprintln(os.Stderr, "已中断, 未完成的输出已清理")
+			})
+			os.Exit(ExitInterrupted)
+		case <-done:
 		}
-		files = append(files, path)
-	}
+	}()
 
-	return files, nil
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
 }
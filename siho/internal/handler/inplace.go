@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ShredWarning 说明覆盖删除的局限性
+const ShredWarning = "注意: 覆盖写入只在传统机械硬盘上较为可靠; " +
+	"SSD/闪存的磨损均衡, 写时复制文件系统 (btrfs, ZFS, APFS), 快照和备份都可能保留原始数据"
+
+// encryptInPlace 加密文件, 校验密文可以正确解密后再删除明文原件
+func (h *Handler) encryptInPlace(inputPath, outputPath string) (string, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return outputPath, fmt.Errorf("无法读取原文件信息: %w", err)
+	}
+
+	if err := h.crypt.Encrypt(inputPath, outputPath); err != nil {
+		return outputPath, err
+	}
+
+	// 校验失败时删除密文, 保留明文原件
+	if err := h.verifyCiphertext(inputPath, outputPath); err != nil {
+		os.Remove(outputPath)
+		return outputPath, err
+	}
+
+	// 让密文继承原件的权限和修改时间, 避免目录中的文件时间被打乱
+	copyAttrs(outputPath, info)
+
+	if h.opts.Shred {
+		if err := overwriteFile(inputPath, info.Size()); err != nil {
+			return outputPath, fmt.Errorf("覆盖原文件失败, 原文件仍保留: %w", err)
+		}
+	}
+
+	if err := os.Remove(inputPath); err != nil {
+		return outputPath, fmt.Errorf("删除原文件失败: %w", err)
+	}
+	return outputPath, nil
+}
+
+// decryptInPlace 解密文件并还原原始文件名, 成功后删除密文
+func (h *Handler) decryptInPlace(inputPath, outputPath string) (string, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return outputPath, fmt.Errorf("无法读取密文信息: %w", err)
+	}
+
+	// age 是带认证的加密, 解密成功即说明内容完整
+	result, err := h.crypt.Decrypt(inputPath, outputPath)
+	if err != nil {
+		return outputPath, err
+	}
+
+	// 还原的文件名恰好是密文自己的名称时, 密文已被明文替换, 再删除就会丢失数据
+	if SameFile(result.OutputPath, inputPath) {
+		return result.OutputPath, fmt.Errorf("解密结果与密文是同一个文件, 已停止删除: %s", inputPath)
+	}
+
+	// 旧格式的密文没有元数据, 退而使用密文自身的权限和修改时间
+	if !result.Restored {
+		copyAttrs(result.OutputPath, info)
+	}
+
+	if err := os.Remove(inputPath); err != nil {
+		return result.OutputPath, fmt.Errorf("删除密文失败: %w", err)
+	}
+	return result.OutputPath, nil
+}
+
+// verifyCiphertext 将密文解密后与原文件的 SHA-256 比对
+func (h *Handler) verifyCiphertext(plainPath, encPath string) error {
+	want, err := hashFile(plainPath)
+	if err != nil {
+		return fmt.Errorf("计算原文件哈希失败: %w", err)
+	}
+
+	// 密文没有在进度中登记, 校验时读取的字节不计入进度
+	hasher := sha256.New()
+	if err := h.crypt.DecryptTo(encPath, hasher); err != nil {
+		return fmt.Errorf("校验密文失败: %w", err)
+	}
+
+	if !bytes.Equal(want, hasher.Sum(nil)) {
+		return errors.New("校验密文失败: 解密结果与原文件不一致")
+	}
+	return nil
+}
+
+// SameFile 判断两个路径是否指向同一个文件, 包括硬链接和不区分大小写的文件系统
+func SameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// hashFile 计算文件的 SHA-256
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
+
+// copyAttrs 尽力复制权限和修改时间, 失败时不影响主流程
+func copyAttrs(path string, info os.FileInfo) {
+	os.Chmod(path, info.Mode().Perm())
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// overwriteFile 用随机数据覆盖文件内容并刷入磁盘
+func overwriteFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.CopyN(f, rand.Reader, size); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"siho/internal/ageheader"
+	"strings"
+	"sync"
+)
+
+// HandleVerify 解密到 io.Discard, 检查文件是否完整以及密码是否正确
+func (h *Handler) HandleVerify() error {
+	verifyFile := func(inputPath, _ string) (string, error) {
+		return inputPath, h.crypt.DecryptTo(inputPath, io.Discard)
+	}
+	return h.processFiles("Verified", nil, verifyFile)
+}
+
+// HeaderInfo info 子命令解析出的头部信息, --json 模式下作为结果记录的 header 字段输出
+type HeaderInfo struct {
+	Stanzas    []string `json:"stanzas"`               // recipient stanza 的类型
+	WorkFactor int      `json:"work_factor,omitempty"` // scrypt 工作因子 (logN), 没有 scrypt stanza 时省略
+}
+
+// HandleInfo 解析 age 头部, 列出 recipient stanza 类型和 scrypt 工作因子
+func (h *Handler) HandleInfo() error {
+	// 头部信息在 worker 中解析, 输出结果记录时再按输入路径取出
+	var headers sync.Map
+	h.annotate = func(rec *Record) {
+		if info, ok := headers.Load(rec.Input); ok {
+			rec.Header = info.(*HeaderInfo)
+			rec.Output = ""
+		}
+	}
+
+	return h.processFiles("Info", nil, func(inputPath, _ string) (string, error) {
+		info, err := readHeaderInfo(inputPath)
+		if err != nil {
+			return inputPath, err
+		}
+		headers.Store(inputPath, info)
+		return info.summary(inputPath), nil
+	})
+}
+
+// readHeaderInfo 读取单个文件的头部信息
+func readHeaderInfo(inputPath string) (*HeaderInfo, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	hdr, err := ageheader.Parse(ageheader.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	return &HeaderInfo{Stanzas: hdr.Types(), WorkFactor: hdr.WorkFactor()}, nil
+}
+
+// summary 将头部信息格式化为一行摘要
+func (info *HeaderInfo) summary(inputPath string) string {
+	summary := fmt.Sprintf("%s: stanzas [%s]", inputPath, strings.Join(info.Stanzas, ", "))
+	if logN := info.WorkFactor; logN > 0 {
+		summary += fmt.Sprintf(", scrypt work factor %d (N=2^%d)", logN, logN)
+	}
+	return summary
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrWrongPassword 密码错误或私钥与密文不匹配
+	ErrWrongPassword = errors.New("密码错误或私钥不匹配")
+	// ErrCorrupt 输入文件已损坏或不是有效的密文
+	ErrCorrupt = errors.New("输入文件已损坏或格式无效")
+)
+
+// 进程的退出码, 便于脚本区分失败原因
+const (
+	ExitOK            = 0 // 全部成功
+	ExitFailure       = 1 // 参数错误等其他失败
+	ExitPartial       = 2 // 部分文件成功, 部分文件失败
+	ExitWrongPassword = 3 // 密码错误或私钥不匹配
+	ExitCorrupt       = 4 // 输入文件损坏
+	ExitIO            = 5 // 读写文件出错
+	ExitInterrupted   = 130
+)
+
+// KeyChecker 可以只解开文件头来确认密码或私钥是否正确的 Cryptor
+type KeyChecker interface {
+	CheckKey(inputPath string) error
+}
+
+// NameReader 可以只解密到元数据头读取原始文件名的 Cryptor, 用于解密前规划输出路径
+type NameReader interface {
+	OriginalName(inputPath string) (string, error)
+}
+
+// BatchError 批量处理中有文件失败
+type BatchError struct {
+	Succeeded int     // 成功处理的文件数
+	Errs      []error // 每个失败文件的错误
+}
+
+func (e *BatchError) Error() string {
+	return errors.Join(e.Errs...).Error()
+}
+
+func (e *BatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// ExitCode 根据错误类型返回退出码
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var batch *BatchError
+	if errors.As(err, &batch) && batch.Succeeded > 0 {
+		return ExitPartial
+	}
+
+	switch {
+	case errors.Is(err, ErrWrongPassword):
+		return ExitWrongPassword
+	case isIOError(err):
+		return ExitIO
+	case errors.Is(err, ErrCorrupt):
+		return ExitCorrupt
+	}
+	return ExitFailure
+}
+
+// isIOError 判断错误是否来自文件系统
+func isIOError(err error) bool {
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var errno syscall.Errno
+	return errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &errno)
+}
+
+// Record --json 模式下每个文件的处理结果, 每行输出一条
+type Record struct {
+	Input      string `json:"input"`
+	Output     string `json:"output,omitempty"`
+	Operation  string `json:"operation"`
+	Status     string `json:"status"` // ok, failed 或 skipped
+	Bytes      int64  `json:"bytes"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+
+	Header *HeaderInfo `json:"header,omitempty"` // info 子命令解析出的头部信息
+}
+
+// 记录的状态
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// newRecord 创建一条处理结果
+func newRecord(opName, inputPath, outputPath string, size int64, elapsed time.Duration, err error) Record {
+	rec := Record{
+		Input:      inputPath,
+		Output:     outputPath,
+		Operation:  strings.ToLower(opName),
+		Status:     StatusOK,
+		Bytes:      size,
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if err != nil {
+		rec.Status = StatusFailed
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// writeRecord 以 JSON Lines 格式将结果写到标准输出
+func writeRecord(rec Record) {
+	json.NewEncoder(os.Stdout).Encode(rec)
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Selection 描述如何从命令行参数和文件列表中挑选待处理的文件
+type Selection struct {
+	FilesFrom string   // 从该文件读取路径列表, "-" 表示标准输入
+	Null      bool     // 路径列表以 NUL 分隔 (配合 find -print0)
+	Recursive bool     // 递归处理目录参数
+	Include   []string // 只保留匹配任一模式的文件
+	Exclude   []string // 排除匹配任一模式的文件或目录
+
+	MinSize   int64         // 最小文件大小 (字节), 0 表示不限制
+	MaxSize   int64         // 最大文件大小 (字节), 0 表示不限制
+	OlderThan time.Duration // 只保留修改时间早于该时长之前的文件, 0 表示不限制
+	NewerThan time.Duration // 只保留修改时间晚于该时长之前的文件, 0 表示不限制
+}
+
+// selectFiles 汇总路径参数和文件列表, 展开目录并应用过滤条件, 返回去重后的文件列表
+func selectFiles(paths []string, sel Selection) ([]string, error) {
+	candidates := paths
+	if sel.FilesFrom != "" {
+		listed, err := readFileList(sel.FilesFrom, sel.Null)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(append([]string{}, paths...), listed...)
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var files []string
+
+	add := func(path, rel string, info fs.FileInfo) {
+		key := filepath.Clean(path)
+		if seen[key] || !sel.matchFile(rel, info, now) {
+			return
+		}
+		seen[key] = true
+		files = append(files, path)
+	}
+
+	for i, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("路径不存在: %s", path)
+			}
+			return nil, fmt.Errorf("无法访问路径 %s: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			// 命令行中直接指定的文件被过滤掉时给出提示, 以免看起来什么都没做
+			explicit := i < len(paths)
+			if explicit && !sel.matchFile(filepath.Base(path), info, now) {
+				warnColor.Fprintf(os.Stderr, "Skipping %s: does not match --include/--exclude or the size/age filters\n", path)
+				continue
+			}
+			add(path, filepath.Base(path), info)
+			continue
+		}
+
+		if !sel.Recursive {
+			warnColor.Fprintf(os.Stderr, "Skipping directory: %s\n", path)
+			continue
+		}
+		if err := sel.walk(path, add); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// walk 递归遍历目录, 对每个普通文件调用 add
+func (sel Selection) walk(root string, add func(path, rel string, info fs.FileInfo)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("遍历目录 %s 失败: %w", path, err)
+		}
+
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = d.Name()
+		}
+
+		if d.IsDir() {
+			// 被排除的目录整体跳过, 根目录本身不参与匹配
+			if path != root && sel.excluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// 符号链接指向的目标是普通文件时才处理
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		add(path, rel, info)
+		return nil
+	})
+}
+
+// matchFile 判断文件是否满足全部过滤条件, rel 为相对于遍历根目录的路径
+func (sel Selection) matchFile(rel string, info fs.FileInfo, now time.Time) bool {
+	// 跳过 siho 自己的临时文件
+	if strings.HasPrefix(info.Name(), atomicfile.TempPrefix) {
+		return false
+	}
+
+	if len(sel.Include) > 0 && !matchAny(sel.Include, rel) {
+		return false
+	}
+	if sel.excluded(rel) {
+		return false
+	}
+
+	size := info.Size()
+	if sel.MinSize > 0 && size < sel.MinSize {
+		return false
+	}
+	if sel.MaxSize > 0 && size > sel.MaxSize {
+		return false
+	}
+
+	age := now.Sub(info.ModTime())
+	if sel.OlderThan > 0 && age < sel.OlderThan {
+		return false
+	}
+	if sel.NewerThan > 0 && age > sel.NewerThan {
+		return false
+	}
+	return true
+}
+
+// excluded 判断路径是否匹配任一排除模式
+func (sel Selection) excluded(rel string) bool {
+	return matchAny(sel.Exclude, rel)
+}
+
+// matchAny 判断路径是否匹配任一 glob 模式
+// 模式中不含路径分隔符时只匹配文件名, 否则匹配相对路径
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		target := filepath.Base(rel)
+		if strings.ContainsRune(pattern, filepath.Separator) {
+			target = rel
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePatterns 检查 glob 模式的语法
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的匹配模式 '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// readFileList 读取路径列表, 每行一个路径, null 为 true 时以 NUL 分隔
+func readFileList(source string, null bool) ([]string, error) {
+	var r io.Reader
+	if source == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件列表失败: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	sep := byte('\n')
+	if null {
+		sep = 0
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, sep); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	var paths []string
+	for scanner.Scan() {
+		path := scanner.Text()
+		// 换行分隔时忽略 Windows 换行符和空行
+		if !null {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path == "" {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取文件列表失败: %w", err)
+	}
+	return paths, nil
+}
+
+// ParseSize 解析带单位的大小, 如 512, 10K, 1.5M, 2G (1024 进制)
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	num := strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	units := map[byte]int64{'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+	if num != "" {
+		if m, ok := units[num[len(num)-1]]; ok {
+			multiplier = m
+			num = num[:len(num)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// ParseAge 解析时长, 除 time.ParseDuration 的格式外还支持以 d 结尾的天数, 如 7d
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("无效的时长: %s", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("无效的时长: %s", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"512", 512, false},
+		{"10K", 10 << 10, false},
+		{"10k", 10 << 10, false},
+		{"10KB", 10 << 10, false},
+		{"10KiB", 10 << 10, false},
+		{"1.5M", 3 << 19, false},
+		{"2G", 2 << 30, false},
+		{"1T", 1 << 40, false},
+		{" 5M ", 5 << 20, false},
+		{"100B", 100, false},
+		{"abc", 0, true},
+		{"-1K", 0, true},
+		{"K", 0, true},
+		{"10X", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) err = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"-2h", 0, true},
+		{"7", 0, true},
+		{"week", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAge(%q) err = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSelectFiles(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	files := []struct {
+		path string
+		size int
+		age  time.Duration
+	}{
+		{"a.txt", 10, time.Hour},
+		{"b.jpg", 2000, 48 * time.Hour},
+		{"docs/c.txt", 500, 10 * 24 * time.Hour},
+		{"docs/d.md", 0, time.Minute},
+		{"cache/e.txt", 10, time.Hour},
+		{"cache/deep/f.txt", 10, time.Hour},
+		{atomicfile.TempPrefix + "g.txt-123", 10, time.Hour},
+	}
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := []string{"a.txt", "b.jpg", "cache/deep/f.txt", "cache/e.txt", "docs/c.txt", "docs/d.md"}
+	tests := []struct {
+		name  string
+		paths []string
+		sel   Selection
+		want  []string // 相对于 root 的路径, 以 / 分隔
+	}{
+		{"directory without recursion is skipped", []string{"."}, Selection{}, nil},
+		{"recursive skips temporary files", []string{"."}, Selection{Recursive: true}, all},
+		{"include by name", []string{"."}, Selection{Recursive: true, Include: []string{"*.txt"}},
+			[]string{"a.txt", "cache/deep/f.txt", "cache/e.txt", "docs/c.txt"}},
+		{"include by relative path", []string{"."}, Selection{Recursive: true, Include: []string{filepath.Join("docs", "*")}},
+			[]string{"docs/c.txt", "docs/d.md"}},
+		{"exclude prunes directories", []string{"."}, Selection{Recursive: true, Exclude: []string{"cache"}},
+			[]string{"a.txt", "b.jpg", "docs/c.txt", "docs/d.md"}},
+		{"exclude wins over include", []string{"."}, Selection{Recursive: true, Include: []string{"*.txt"}, Exclude: []string{"c.*"}},
+			[]string{"a.txt", "cache/deep/f.txt", "cache/e.txt"}},
+		{"min size", []string{"."}, Selection{Recursive: true, MinSize: 100}, []string{"b.jpg", "docs/c.txt"}},
+		{"max size", []string{"."}, Selection{Recursive: true, MaxSize: 10},
+			[]string{"a.txt", "cache/deep/f.txt", "cache/e.txt", "docs/d.md"}},
+		{"older than", []string{"."}, Selection{Recursive: true, OlderThan: 24 * time.Hour}, []string{"b.jpg", "docs/c.txt"}},
+		{"newer than", []string{"."}, Selection{Recursive: true, NewerThan: 30 * time.Minute}, []string{"docs/d.md"}},
+		{"age window", []string{"."}, Selection{Recursive: true, OlderThan: 24 * time.Hour, NewerThan: 7 * 24 * time.Hour}, []string{"b.jpg"}},
+		{"explicit files are filtered too", []string{"a.txt", "b.jpg"}, Selection{Include: []string{"*.jpg"}}, []string{"b.jpg"}},
+		{"duplicates are removed", []string{"a.txt", "./a.txt", "docs"}, Selection{Recursive: true, Include: []string{"a.txt"}}, []string{"a.txt"}},
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectFiles(tt.paths, tt.sel)
+			if err != nil {
+				t.Fatalf("selectFiles: %v", err)
+			}
+			var rels []string
+			for _, path := range got {
+				rels = append(rels, filepath.ToSlash(filepath.Clean(path)))
+			}
+			slices.Sort(rels)
+			if !slices.Equal(rels, tt.want) {
+				t.Errorf("selectFiles(%v) = %v, want %v", tt.paths, rels, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectFiles([]string{"missing.txt"}, Selection{}); err == nil {
+		t.Error("selectFiles with a missing path succeeded")
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		data string
+		null bool
+		want []string
+	}{
+		{"lines", "a.txt\nb c.txt\n", false, []string{"a.txt", "b c.txt"}},
+		{"windows line endings and blank lines", "a.txt\r\n\r\nb.txt", false, []string{"a.txt", "b.txt"}},
+		{"nul separated", "a\nb.txt\x00c.txt\x00", true, []string{"a\nb.txt", "c.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "list")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readFileList(path, tt.null)
+			if err != nil {
+				t.Fatalf("readFileList: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("readFileList = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+//go:build linux || darwin
+
+package mount
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// errTooLarge 单个文件的明文超过大小上限
+	errTooLarge = errors.New("明文超过大小上限")
+	// errNoMemory 打开的文件占用的明文已达到总量上限, 无法再容纳这个文件
+	errNoMemory = errors.New("内存中的明文已达到总量上限")
+)
+
+// cache 缓存每个密文文件解密后的明文
+// 明文只保存在内存中, 不会写入磁盘; 所有明文的总量不超过 maxSize,
+// 超出时先丢弃最久未使用的闲置明文, 同一个文件再次打开时直接复用, 不再重复派生密钥
+type cache struct {
+	crypt   Cryptor
+	maxSize int64 // 内存中明文的总量上限, 同时也是单个文件的上限, 0 表示不限制
+
+	mu      sync.Mutex // 保护 entries, clock 以及每个条目的 plain 和 used
+	entries map[string]*cacheEntry
+	clock   int64
+}
+
+// cacheEntry 单个密文文件的缓存
+type cacheEntry struct {
+	mu      sync.Mutex // 保证同一个文件同时只解密一次
+	modTime time.Time  // 密文的修改时间和大小, 任一变化时缓存失效
+	ctSize  int64
+	size    int64 // 解密过一次后得知的明文大小, -1 表示未知
+
+	plain *plaintext // 已解密的明文, 尚未解密或已被丢弃时为 nil
+	used  int64      // 最近一次使用的顺序, 用于丢弃闲置的明文
+}
+
+// plaintext 解密到内存中的明文
+type plaintext struct {
+	data []byte
+	refs int // 缓存本身和每个打开的 fileHandle 各持有一个引用
+}
+
+func newCache(c Cryptor, maxSize int64) *cache {
+	return &cache{crypt: c, maxSize: maxSize, entries: make(map[string]*cacheEntry)}
+}
+
+// entry 返回路径对应的缓存条目, 不存在时创建
+func (c *cache) entry(path string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok {
+		e = &cacheEntry{size: -1}
+		c.entries[path] = e
+	}
+	return e
+}
+
+// validate 密文已变化时丢弃缓存, 调用方需持有 e.mu
+func (c *cache) validate(e *cacheEntry, info os.FileInfo) {
+	if e.modTime.Equal(info.ModTime()) && e.ctSize == info.Size() {
+		return
+	}
+	e.modTime, e.ctSize = info.ModTime(), info.Size()
+	e.size = -1
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.plain != nil {
+		c.release(e.plain)
+		e.plain = nil
+	}
+}
+
+// Size 返回明文大小, 文件还没有被打开过时返回 -1
+// 大小只能在解密后得知, 列目录时不为此派生密钥
+func (c *cache) Size(path string, info os.FileInfo) int64 {
+	e := c.entry(path)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c.validate(e, info)
+	return e.size
+}
+
+// Open 返回解密后的明文, 使用完毕后需要调用 Release
+// exact 表示明文大小与之前报告给内核的大小一致
+func (c *cache) Open(path string, info os.FileInfo) (p *plaintext, exact bool, err error) {
+	e := c.entry(path)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c.validate(e, info)
+
+	c.mu.Lock()
+	if p = e.plain; p != nil {
+		p.refs++
+		c.clock++
+		e.used = c.clock
+	}
+	c.mu.Unlock()
+	if p != nil {
+		return p, true, nil
+	}
+
+	if c.maxSize > 0 && e.size > c.maxSize {
+		return nil, false, errTooLarge
+	}
+
+	var buf bytes.Buffer
+	w := &limitWriter{w: &buf, limit: c.maxSize}
+	if err := c.crypt.DecryptTo(path, w); err != nil {
+		clear(buf.Bytes())
+		if w.exceeded {
+			return nil, false, errTooLarge
+		}
+		return nil, false, err
+	}
+
+	p = &plaintext{data: buf.Bytes(), refs: 2}
+	exact = e.size == int64(len(p.data))
+	e.size = int64(len(p.data))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.reserve(e.size) {
+		clear(p.data)
+		return nil, false, errNoMemory
+	}
+	e.plain = p
+	c.clock++
+	e.used = c.clock
+	return p, exact, nil
+}
+
+// Release 释放 Open 返回的明文
+func (c *cache) Release(p *plaintext) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.release(p)
+}
+
+// release 减少引用计数, 没有引用时清零明文, 调用方需持有 c.mu
+func (c *cache) release(p *plaintext) {
+	p.refs--
+	if p.refs == 0 {
+		clear(p.data)
+		p.data = nil
+	}
+}
+
+// reserve 为 n 字节的新明文腾出空间, 按最近使用顺序从旧到新丢弃闲置的明文
+// 丢弃所有闲置明文后仍放不下时返回 false, 调用方需持有 c.mu
+func (c *cache) reserve(n int64) bool {
+	if c.maxSize <= 0 {
+		return true
+	}
+	for {
+		total := n
+		var oldest *cacheEntry
+		for _, e := range c.entries {
+			if e.plain == nil {
+				continue
+			}
+			total += int64(len(e.plain.data))
+			if e.plain.refs == 1 && (oldest == nil || e.used < oldest.used) {
+				oldest = e
+			}
+		}
+		if total <= c.maxSize {
+			return true
+		}
+		if oldest == nil {
+			return false
+		}
+		c.release(oldest.plain)
+		oldest.plain = nil
+	}
+}
+
+// Cleanup 释放缓存持有的所有明文
+func (c *cache) Cleanup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if e.plain != nil {
+			c.release(e.plain)
+			e.plain = nil
+		}
+	}
+}
+
+// limitWriter 写入超过 limit 字节时返回 errTooLarge, limit 为 0 表示不限制
+type limitWriter struct {
+	w        io.Writer
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.limit > 0 && l.written+int64(len(p)) > l.limit {
+		l.exceeded = true
+		return 0, errTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,288 @@
+//go:build linux || darwin
+
+package mount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"siho/internal/atomicfile"
+	"siho/internal/progress"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+var errorColor = color.New(color.FgRed)
+
+const (
+	attrTimeout = time.Second // 内核缓存目录项和属性的时长
+	encSuffix   = "_enc"      // 密文文件名的后缀
+)
+
+// Cryptor 挂载时使用的解密接口
+type Cryptor interface {
+	DecryptTo(inputPath string, w io.Writer) error
+}
+
+// Options 挂载选项
+type Options struct {
+	MaxSize int64 // 内存中明文的总量上限, 同时也是单个文件的上限, 0 表示不限制
+	Debug   bool  // 输出 FUSE 调试日志
+}
+
+// Server 已挂载的文件系统
+type Server struct {
+	*fuse.Server
+	cache *cache
+}
+
+// Mount 将加密目录以只读方式挂载到 mountpoint, 文件在打开时按需解密到内存中, 明文不会写入磁盘
+// 只显示以 "_enc" 结尾的密文, 文件名去掉该后缀; 文件被打开之前显示的是密文大小,
+// 列目录时不需要派生密钥, 解密后改为明文大小, 直到密文发生变化
+func Mount(srcDir, mountpoint string, c Cryptor, opts Options) (*Server, error) {
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return nil, fmt.Errorf("无法访问加密目录: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("不是目录: %s", srcDir)
+	}
+
+	cache := newCache(c, opts.MaxSize)
+	root := &dirNode{path: srcDir, cache: cache}
+	timeout := attrTimeout
+	server, err := fs.Mount(mountpoint, root, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName:      srcDir,
+			Name:        "siho",
+			Options:     []string{"ro"},
+			DirectMount: true,
+			Debug:       opts.Debug,
+		},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+	})
+	if err != nil {
+		cache.Cleanup()
+		return nil, fmt.Errorf("挂载失败: %w", err)
+	}
+	return &Server{Server: server, cache: cache}, nil
+}
+
+// Cleanup 卸载后释放所有解密出的明文
+func (s *Server) Cleanup() error {
+	s.cache.Cleanup()
+	return nil
+}
+
+// dirNode 加密目录中的子目录, 原样映射
+type dirNode struct {
+	fs.Inode
+	path  string
+	cache *cache
+}
+
+var (
+	_ fs.NodeReaddirer = (*dirNode)(nil)
+	_ fs.NodeLookuper  = (*dirNode)(nil)
+	_ fs.NodeGetattrer = (*dirNode)(nil)
+)
+
+// entries 列出目录中可显示的条目, 返回显示名称到真实文件名的映射
+// 多个密文映射到同一个名称时, 只保留按文件名排序的第一个
+func (d *dirNode) entries() (map[string]os.DirEntry, []string, error) {
+	list, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byName := make(map[string]os.DirEntry, len(list))
+	var names []string
+	for _, e := range list {
+		// 跳过 siho 自己的临时文件
+		if strings.HasPrefix(e.Name(), atomicfile.TempPrefix) {
+			continue
+		}
+
+		// 只显示密文, 其他文件解密必然失败
+		name := e.Name()
+		switch {
+		case e.IsDir():
+		case e.Type().IsRegular() && strings.HasSuffix(name, encSuffix) && name != encSuffix:
+			name = strings.TrimSuffix(name, encSuffix)
+		default:
+			continue
+		}
+
+		if _, ok := byName[name]; ok {
+			continue
+		}
+		byName[name] = e
+		names = append(names, name)
+	}
+	return byName, names, nil
+}
+
+func (d *dirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	byName, names, err := d.entries()
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+
+	list := make([]fuse.DirEntry, 0, len(names))
+	for _, name := range names {
+		mode := uint32(fuse.S_IFREG)
+		if byName[name].IsDir() {
+			mode = fuse.S_IFDIR
+		}
+		list = append(list, fuse.DirEntry{Name: name, Mode: mode})
+	}
+	return fs.NewListDirStream(list), 0
+}
+
+func (d *dirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	byName, _, err := d.entries()
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	e, ok := byName[name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+
+	path := filepath.Join(d.path, e.Name())
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+
+	if info.IsDir() {
+		child := &dirNode{path: path, cache: d.cache}
+		fillAttr(&out.Attr, info, info.Size())
+		return d.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+	}
+
+	child := &fileNode{path: path, cache: d.cache}
+	fillAttr(&out.Attr, info, child.size(info))
+	return d.NewInode(ctx, child, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+}
+
+func (d *dirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return fs.ToErrno(err)
+	}
+	fillAttr(&out.Attr, info, info.Size())
+	return 0
+}
+
+// fileNode 单个密文文件, 明文大小和解密结果由 cache 缓存
+type fileNode struct {
+	fs.Inode
+	path  string
+	cache *cache
+}
+
+var (
+	_ fs.NodeOpener    = (*fileNode)(nil)
+	_ fs.NodeGetattrer = (*fileNode)(nil)
+)
+
+// size 返回明文大小, 文件还没有被解密过时返回密文大小
+func (n *fileNode) size(info os.FileInfo) int64 {
+	if size := n.cache.Size(n.path, info); size >= 0 {
+		return size
+	}
+	return info.Size()
+}
+
+func (n *fileNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	info, err := os.Stat(n.path)
+	if err != nil {
+		return fs.ToErrno(err)
+	}
+	if h, ok := f.(*fileHandle); ok {
+		fillAttr(&out.Attr, info, int64(len(h.plain.data)))
+		return 0
+	}
+	fillAttr(&out.Attr, info, n.size(info))
+	return 0
+}
+
+func (n *fileNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_TRUNC|syscall.O_APPEND) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+
+	info, err := os.Stat(n.path)
+	if err != nil {
+		return nil, 0, fs.ToErrno(err)
+	}
+
+	p, exact, err := n.cache.Open(n.path, info)
+	if errors.Is(err, errTooLarge) {
+		errorColor.Fprintf(os.Stderr, "Failed to decrypt %s: plaintext exceeds --max-size (%s)\n", n.path, progress.FormatBytes(n.cache.maxSize))
+		return nil, 0, syscall.EFBIG
+	}
+	if errors.Is(err, errNoMemory) {
+		errorColor.Fprintf(os.Stderr, "Failed to decrypt %s: open files already hold --max-size (%s) of plaintext\n", n.path, progress.FormatBytes(n.cache.maxSize))
+		return nil, 0, syscall.ENOMEM
+	}
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Failed to decrypt %s: %v\n", n.path, err)
+		return nil, 0, syscall.EIO
+	}
+
+	// 第一次打开时内核看到的是密文大小, 可能按错误的大小截断读取, 改用 direct I/O
+	var fuseFlags uint32
+	if !exact {
+		fuseFlags = fuse.FOPEN_DIRECT_IO
+	}
+	return &fileHandle{cache: n.cache, plain: p}, fuseFlags, 0
+}
+
+// fileHandle 打开的文件, 从内存中的明文读取
+type fileHandle struct {
+	cache *cache
+	plain *plaintext
+}
+
+var (
+	_ fs.FileReader   = (*fileHandle)(nil)
+	_ fs.FileReleaser = (*fileHandle)(nil)
+)
+
+func (h *fileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	data := h.plain.data
+	if off >= int64(len(data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	n := copy(dest, data[off:])
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// Release 关闭文件时释放明文, 没有其他引用时清零
+func (h *fileHandle) Release(ctx context.Context) syscall.Errno {
+	h.cache.Release(h.plain)
+	return 0
+}
+
+// fillAttr 根据底层文件填充属性, 去掉所有写权限
+func fillAttr(out *fuse.Attr, info os.FileInfo, size int64) {
+	var st syscall.Stat_t
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+		st = *sys
+	}
+	out.FromStat(&st)
+	out.Size = uint64(size)
+	out.Blocks = (out.Size + 511) / 512
+	out.Mode = out.Mode &^ 0222
+}
@@ -0,0 +1,36 @@
+//go:build !linux && !darwin
+
+package mount
+
+import (
+	"errors"
+	"io"
+)
+
+// Cryptor 挂载时使用的解密接口
+type Cryptor interface {
+	DecryptTo(inputPath string, w io.Writer) error
+}
+
+// Options 挂载选项
+type Options struct {
+	MaxSize int64 // 内存中明文的总量上限, 同时也是单个文件的上限, 0 表示不限制
+	Debug   bool  // 输出 FUSE 调试日志
+}
+
+// Server 已挂载的文件系统, 当前平台不支持
+type Server struct{}
+
+// Mount 当前平台不支持 FUSE
+func Mount(srcDir, mountpoint string, c Cryptor, opts Options) (*Server, error) {
+	return nil, errors.New("当前平台不支持 mount")
+}
+
+// Unmount 当前平台不支持 FUSE
+func (s *Server) Unmount() error { return nil }
+
+// Wait 当前平台不支持 FUSE
+func (s *Server) Wait() {}
+
+// Cleanup 当前平台不支持 FUSE
+func (s *Server) Cleanup() error { return nil }
@@ -0,0 +1,205 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	barWidth      = 24
+	refreshPeriod = 200 * time.Millisecond
+)
+
+// fileState 单个文件的处理进度
+type fileState struct {
+	name  string
+	size  int64
+	done  int64
+	order int // 开始处理的顺序, 用于稳定地排列进度条
+}
+
+// Tracker 跟踪 worker pool 中每个文件的字节进度和整体吞吐量
+// 进度条写到 out (通常是终端的标准错误), 每次刷新时覆盖上一次的输出
+type Tracker struct {
+	mu       sync.Mutex
+	out      io.Writer
+	enabled  bool
+	files    map[string]*fileState
+	started  int
+	total    int   // 文件总数
+	finished int   // 已完成的文件数
+	bytes    int64 // 所有文件累计处理的字节数
+	begin    time.Time
+	lines    int // 上一次绘制的行数
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewTracker 创建进度跟踪器, enabled 为 false 时所有方法都只做记录, 不绘制
+func NewTracker(out io.Writer, total int, enabled bool) *Tracker {
+	return &Tracker{
+		out:     out,
+		enabled: enabled,
+		files:   make(map[string]*fileState),
+		total:   total,
+		begin:   time.Now(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Run 启动后台刷新, 需要配合 Stop 使用
+func (t *Tracker) Run() {
+	go func() {
+		defer close(t.stopped)
+		if !t.enabled {
+			<-t.stop
+			return
+		}
+
+		ticker := time.NewTicker(refreshPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.mu.Lock()
+				t.redraw()
+				t.mu.Unlock()
+			case <-t.stop:
+				t.mu.Lock()
+				t.clear()
+				t.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止刷新并擦除进度条
+func (t *Tracker) Stop() {
+	close(t.stop)
+	<-t.stopped
+}
+
+// Start 登记一个开始处理的文件
+func (t *Tracker) Start(path string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started++
+	t.files[path] = &fileState{name: filepath.Base(path), size: size, order: t.started}
+}
+
+// Add 累加某个文件已处理的字节数, 可以被多个 goroutine 并发调用
+// 只统计通过 Start 登记的文件, 处理过程中读取的其他文件 (如原地加密后校验的密文) 不计入进度,
+// 否则总字节数会超过按输入文件大小计算的总量
+func (t *Tracker) Add(path string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[path]
+	if !ok {
+		return
+	}
+	f.done += n
+	t.bytes += n
+}
+
+// Finish 标记文件处理完毕并移除它的进度条
+func (t *Tracker) Finish(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished++
+	delete(t.files, path)
+}
+
+// Log 在进度条上方输出一行日志, 避免与进度条互相覆盖
+func (t *Tracker) Log(print func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+	print()
+	t.redraw()
+}
+
+// Summary 返回累计字节数和平均吞吐量 (字节/秒)
+func (t *Tracker) Summary() (int64, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	elapsed := time.Since(t.begin).Seconds()
+	if elapsed <= 0 {
+		return t.bytes, 0
+	}
+	return t.bytes, float64(t.bytes) / elapsed
+}
+
+// clear 擦除上一次绘制的进度条, 调用方需持有锁
+func (t *Tracker) clear() {
+	if !t.enabled || t.lines == 0 {
+		return
+	}
+	// 光标上移并清除到屏幕末尾
+	fmt.Fprintf(t.out, "\033[%dA\033[J", t.lines)
+	t.lines = 0
+}
+
+// redraw 重新绘制所有进度条, 调用方需持有锁
+func (t *Tracker) redraw() {
+	if !t.enabled {
+		return
+	}
+	t.clear()
+
+	active := make([]*fileState, 0, len(t.files))
+	for _, f := range t.files {
+		active = append(active, f)
+	}
+	slices.SortFunc(active, func(a, b *fileState) int { return a.order - b.order })
+
+	var b strings.Builder
+	for _, f := range active {
+		fmt.Fprintf(&b, "%s\n", renderFile(f))
+	}
+
+	rate := 0.0
+	if elapsed := time.Since(t.begin).Seconds(); elapsed > 0 {
+		rate = float64(t.bytes) / elapsed
+	}
+	fmt.Fprintf(&b, "[%d/%d] %s, %s/s\n", t.finished, t.total, FormatBytes(t.bytes), FormatBytes(int64(rate)))
+
+	io.WriteString(t.out, b.String())
+	t.lines = len(active) + 1
+}
+
+// renderFile 渲染单个文件的进度条
+func renderFile(f *fileState) string {
+	ratio := 1.0
+	if f.size > 0 {
+		ratio = min(float64(f.done)/float64(f.size), 1)
+	}
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	name := f.name
+	if r := []rune(name); len(r) > 32 {
+		name = string(r[:29]) + "..."
+	}
+	return fmt.Sprintf("%-32s [%s] %3.0f%% %s/%s", name, bar, ratio*100, FormatBytes(min(f.done, f.size)), FormatBytes(f.size))
+}
+
+// FormatBytes 将字节数格式化为易读的字符串
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,139 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// MaxShares 份额的最大数量, 份额的横坐标为 1..255 的单个字节
+const MaxShares = 255
+
+// expTable 和 logTable 是 GF(2^8) (约化多项式 x^8+x^4+x^3+x+1) 以 3 为生成元的指数表和对数表
+var expTable, logTable = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+		// x *= 3, 即 x ^ (x * 2)
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}()
+
+// mul GF(2^8) 乘法
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+// div GF(2^8) 除法, b 不能为 0
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+// Split 将 secret 拆分为 n 份, 任意 k 份即可还原
+// 每份的第一个字节是横坐标, 其后是与 secret 等长的纵坐标
+func Split(secret []byte, n, k int) ([][]byte, error) {
+	if err := ValidateParams(n, k); err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("待拆分的秘密为空")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	// 每个字节使用独立的 k-1 次随机多项式, 常数项为秘密本身
+	coeffs := make([]byte, k)
+	defer clear(coeffs)
+	for j, s := range secret {
+		coeffs[0] = s
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("生成随机数失败: %w", err)
+		}
+		for _, share := range shares {
+			share[j+1] = eval(coeffs, share[0])
+		}
+	}
+	return shares, nil
+}
+
+// eval 用秦九韶算法计算多项式在 x 处的值
+func eval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// Combine 用拉格朗日插值还原秘密, 份额数量不足门限时得到的是无意义的数据
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("至少需要两份份额")
+	}
+
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("份额数据过短")
+	}
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("份额长度不一致")
+		}
+		x := share[0]
+		if x == 0 {
+			return nil, errors.New("份额的横坐标无效")
+		}
+		if seen[x] {
+			return nil, fmt.Errorf("重复的份额: #%d", x)
+		}
+		seen[x] = true
+	}
+
+	secret := make([]byte, size-1)
+	for i, si := range shares {
+		// 拉格朗日基函数在 0 处的值: prod(xj / (xj - xi)), 减法即异或
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = mul(basis, div(sj[0], sj[0]^si[0]))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(si[b+1], basis)
+		}
+	}
+	return secret, nil
+}
+
+// ValidateParams 检查份额数量和门限
+func ValidateParams(n, k int) error {
+	if k < 2 {
+		return fmt.Errorf("门限至少为 2: %d", k)
+	}
+	if n < k {
+		return fmt.Errorf("份额数量 (%d) 不能小于门限 (%d)", n, k)
+	}
+	if n > MaxShares {
+		return fmt.Errorf("份额数量不能超过 %d: %d", MaxShares, n)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package shamir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("AGE-SECRET-KEY-1QQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQQ")
+
+	tests := []struct {
+		n, k int
+	}{
+		{2, 2},
+		{3, 2},
+		{5, 3},
+		{10, 10},
+		{MaxShares, 2},
+		{MaxShares, 17},
+	}
+
+	for _, tt := range tests {
+		shares, err := Split(secret, tt.n, tt.k)
+		if err != nil {
+			t.Fatalf("Split(n=%d, k=%d): %v", tt.n, tt.k, err)
+		}
+		if len(shares) != tt.n {
+			t.Fatalf("Split(n=%d, k=%d) returned %d shares", tt.n, tt.k, len(shares))
+		}
+
+		// 任意 k 份都能还原, 这里取开头、结尾和间隔的份额
+		subsets := [][][]byte{shares[:tt.k], shares[tt.n-tt.k:]}
+		if stride := tt.n / tt.k; stride > 1 {
+			var spread [][]byte
+			for i := 0; i < tt.k; i++ {
+				spread = append(spread, shares[i*stride])
+			}
+			subsets = append(subsets, spread)
+		}
+		for _, subset := range subsets {
+			got, err := Combine(subset)
+			if err != nil {
+				t.Fatalf("Combine %d of %d shares (k=%d): %v", len(subset), tt.n, tt.k, err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("Combine %d of %d shares (k=%d) = %q, want the secret", len(subset), tt.n, tt.k, got)
+			}
+		}
+
+		// 少于门限的份额得不到秘密, 只有一份时直接报错
+		got, err := Combine(shares[:tt.k-1])
+		if tt.k-1 < 2 {
+			if err == nil {
+				t.Errorf("Combine with a single share (k=%d) succeeded", tt.k)
+			}
+		} else if err != nil {
+			t.Errorf("Combine %d shares (k=%d): %v", tt.k-1, tt.k, err)
+		} else if bytes.Equal(got, secret) {
+			t.Errorf("Combine %d shares (k=%d) recovered the secret below the threshold", tt.k-1, tt.k)
+		}
+	}
+}
+
+func TestSplitInvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret []byte
+		n, k   int
+	}{
+		{"threshold below 2", []byte("s"), 3, 1},
+		{"fewer shares than threshold", []byte("s"), 2, 3},
+		{"too many shares", []byte("s"), MaxShares + 1, 2},
+		{"empty secret", nil, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Split(tt.secret, tt.n, tt.k); err == nil {
+				t.Errorf("Split(n=%d, k=%d) succeeded", tt.n, tt.k)
+			}
+		})
+	}
+}
+
+func TestCombineInvalidShares(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		shares  [][]byte
+		wantErr string
+	}{
+		{"duplicate share", [][]byte{shares[0], shares[0]}, "重复的份额"},
+		{"length mismatch", [][]byte{shares[0], shares[1][:4]}, "长度不一致"},
+		{"zero index", [][]byte{append([]byte{0}, shares[0][1:]...), shares[1]}, "横坐标无效"},
+		{"too short", [][]byte{{1}, {2}}, "过短"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Combine(tt.shares)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Combine error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseShare(t *testing.T) {
+	data, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := Share{Index: 2, Total: 3, Threshold: 2, Recipient: "age1example", Data: data[1]}
+
+	s, err := ParseShare(valid.Encode())
+	if err != nil {
+		t.Fatalf("ParseShare of an encoded share: %v", err)
+	}
+	if s.Index != valid.Index || s.Total != valid.Total || s.Threshold != valid.Threshold ||
+		s.Recipient != valid.Recipient || !bytes.Equal(s.Data, valid.Data) {
+		t.Errorf("ParseShare round trip = %+v, want %+v", s, valid)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"not pem", []byte("hello"), "不是 siho 份额文件"},
+		{"index does not match data", func() []byte { s := valid; s.Index = 1; return s.Encode() }(), "与编号不一致"},
+		{"index out of range", func() []byte { s := valid; s.Index = 300; return s.Encode() }(), "与编号不一致"},
+		{"threshold above total", func() []byte { s := valid; s.Threshold = 4; return s.Encode() }(), "不能小于门限"},
+		{"missing data", func() []byte { s := valid; s.Data = []byte{2}; return s.Encode() }(), "与编号不一致"},
+		{"non-numeric index", []byte(strings.Replace(string(valid.Encode()), "Index: 2", "Index: two", 1)), "Index 字段无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseShare(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseShare error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package shamir
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// pemType 份额文件的 PEM 块类型
+const pemType = "SIHO SHARE"
+
+// Share 份额文件的内容
+type Share struct {
+	Index     int    // 份额编号, 即横坐标
+	Total     int    // 拆分出的份额总数
+	Threshold int    // 还原所需的最少份额数
+	Recipient string // 被拆分私钥对应的公钥, 用于确认各份额属于同一次拆分
+	Data      []byte // 横坐标加纵坐标
+}
+
+// Encode 将份额编码为 PEM 风格的文本
+func (s *Share) Encode() []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type: pemType,
+		Headers: map[string]string{
+			"Index":     strconv.Itoa(s.Index),
+			"Total":     strconv.Itoa(s.Total),
+			"Threshold": strconv.Itoa(s.Threshold),
+			"Recipient": s.Recipient,
+		},
+		Bytes: s.Data,
+	})
+}
+
+// ParseShare 解析份额文件
+func ParseShare(data []byte) (*Share, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemType {
+		return nil, errors.New("不是 siho 份额文件")
+	}
+
+	s := &Share{Recipient: block.Headers["Recipient"], Data: block.Bytes}
+	for name, dst := range map[string]*int{"Index": &s.Index, "Total": &s.Total, "Threshold": &s.Threshold} {
+		v, err := strconv.Atoi(block.Headers[name])
+		if err != nil {
+			return nil, fmt.Errorf("份额文件的 %s 字段无效", name)
+		}
+		*dst = v
+	}
+
+	if len(s.Data) < 2 || int(s.Data[0]) != s.Index {
+		return nil, errors.New("份额数据与编号不一致")
+	}
+	if err := ValidateParams(s.Total, s.Threshold); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CombineShares 检查份额属于同一次拆分且数量达到门限, 然后还原秘密
+func CombineShares(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("未提供份额")
+	}
+
+	first := shares[0]
+	data := make([][]byte, 0, len(shares))
+	for _, s := range shares {
+		if s.Recipient != first.Recipient || s.Threshold != first.Threshold || s.Total != first.Total {
+			return nil, fmt.Errorf("份额不属于同一次拆分: %s (%d/%d) 与 %s (%d/%d)",
+				first.Recipient, first.Threshold, first.Total, s.Recipient, s.Threshold, s.Total)
+		}
+		data = append(data, s.Data)
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("份额不足: 需要 %d 份, 只提供了 %d 份", first.Threshold, len(shares))
+	}
+	return Combine(data)
+}
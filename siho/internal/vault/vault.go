@@ -0,0 +1,317 @@
+package vault
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"siho/internal/ageheader"
+	"siho/internal/atomicfile"
+	"siho/internal/cryptor"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+)
+
+const (
+	RecipientsFile = ".siho-recipients" // 团队成员的公钥列表, 每行一个
+	ManifestFile   = ".siho-vault.json" // 记录每个文件加密时使用的接收者集合
+	encSuffix      = "_enc"             // 保险库中加密文件的后缀
+)
+
+// 文件相对于当前接收者集合的状态
+const (
+	StateCurrent   = "current"   // 已加密给当前的接收者集合
+	StateOutdated  = "outdated"  // 加密时的接收者集合与当前不同, 需要 rekey
+	StateUntracked = "untracked" // 清单中没有记录, 无法判断
+	StateMissing   = "missing"   // 清单中有记录, 但文件已不存在
+	StateBroken    = "broken"    // 无法读取文件的 age 头部
+)
+
+// Entry 清单中单个文件的记录
+type Entry struct {
+	Recipients string    `json:"recipients"` // 接收者集合的指纹
+	Updated    time.Time `json:"updated"`    // 最近一次加密的时间
+}
+
+// manifest 保险库清单
+type manifest struct {
+	Files map[string]Entry `json:"files"`
+}
+
+// FileStatus 单个文件的状态
+type FileStatus struct {
+	Name   string
+	State  string
+	Entry  Entry
+	Reason string // 状态不是最新时的原因
+}
+
+// Vault 一个包含公钥列表的目录, 其中的文件加密给列表中的所有公钥
+type Vault struct {
+	Dir         string
+	Recipients  []age.Recipient
+	Fingerprint string // 当前接收者集合的指纹
+	manifest    manifest
+}
+
+// Init 在目录中创建公钥列表文件, 文件已存在时返回错误
+func Init(dir string, pubkeys []string) error {
+	for _, key := range pubkeys {
+		if _, err := cryptor.ParseRecipient(key); err != nil {
+			return fmt.Errorf("无效的公钥 '%s': %w", key, err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建保险库目录失败: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("# siho vault recipients, one public key per line\n")
+	for _, key := range pubkeys {
+		b.WriteString(key + "\n")
+	}
+
+	path := filepath.Join(dir, RecipientsFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("保险库已存在: %s", path)
+		}
+		return fmt.Errorf("创建公钥列表失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("写入公钥列表失败: %w", err)
+	}
+	return nil
+}
+
+// Open 读取目录中的公钥列表和清单
+func Open(dir string) (*Vault, error) {
+	f, err := os.Open(filepath.Join(dir, RecipientsFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s 不是保险库, 缺少 %s (使用 'siho vault init' 创建)", dir, RecipientsFile)
+		}
+		return nil, fmt.Errorf("打开公钥列表失败: %w", err)
+	}
+	defer f.Close()
+
+	lines, err := cryptor.ReadRecipientLines(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s 中没有公钥", RecipientsFile)
+	}
+
+	v := &Vault{Dir: dir, Fingerprint: fingerprint(lines)}
+	for _, line := range lines {
+		r, err := cryptor.ParseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("无效的公钥 '%s': %w", line, err)
+		}
+		v.Recipients = append(v.Recipients, r)
+	}
+
+	if err := v.loadManifest(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// fingerprint 计算接收者集合的指纹, 与顺序和重复无关
+func fingerprint(lines []string) string {
+	keys := slices.Clone(lines)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadManifest 读取清单, 不存在时视为空清单
+func (v *Vault) loadManifest() error {
+	v.manifest = manifest{Files: make(map[string]Entry)}
+
+	data, err := os.ReadFile(filepath.Join(v.Dir, ManifestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("读取保险库清单失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &v.manifest); err != nil {
+		return fmt.Errorf("解析保险库清单失败: %w", err)
+	}
+	if v.manifest.Files == nil {
+		v.manifest.Files = make(map[string]Entry)
+	}
+	return nil
+}
+
+// Record 记录文件已加密给当前的接收者集合
+func (v *Vault) Record(path string) {
+	v.manifest.Files[filepath.Base(path)] = Entry{
+		Recipients: v.Fingerprint,
+		Updated:    time.Now().UTC(),
+	}
+}
+
+// Save 原子地写入清单, 同时移除已不存在的文件记录
+func (v *Vault) Save() error {
+	for name := range v.manifest.Files {
+		if _, err := os.Stat(filepath.Join(v.Dir, name)); errors.Is(err, os.ErrNotExist) {
+			delete(v.manifest.Files, name)
+		}
+	}
+
+	data, err := json.MarshalIndent(v.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化保险库清单失败: %w", err)
+	}
+
+	f, err := atomicfile.Create(filepath.Join(v.Dir, ManifestFile))
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入保险库清单失败: %w", err)
+	}
+	f.Chmod(0644)
+	return f.Commit(true)
+}
+
+// Files 返回保险库中所有加密文件的路径
+func (v *Vault) Files() ([]string, error) {
+	entries, err := os.ReadDir(v.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取保险库目录失败: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasSuffix(name, encSuffix) {
+			continue
+		}
+		if strings.HasPrefix(name, atomicfile.TempPrefix) {
+			continue
+		}
+		files = append(files, filepath.Join(v.Dir, name))
+	}
+	return files, nil
+}
+
+// Status 列出每个文件相对于当前接收者集合的状态
+// 除了清单中的记录外还会检查文件头中的 stanza: SSH 接收者可以逐个比较,
+// X25519 的 stanza 不含接收者信息, 只能比较类型和数量, 同样数量的 X25519 接收者之间的替换
+// 只能依靠清单中记录的指纹发现, 文件被替换成加密给其他同样数量 X25519 公钥的文件时无法察觉
+func (v *Vault) Status() ([]FileStatus, error) {
+	files, err := v.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := v.stanzaKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []FileStatus
+	seen := make(map[string]bool)
+	for _, path := range files {
+		name := filepath.Base(path)
+		seen[name] = true
+
+		entry, ok := v.manifest.Files[name]
+		status := FileStatus{Name: name, State: StateCurrent, Entry: entry}
+
+		actual, err := fileStanzaKeys(path)
+		switch {
+		case err != nil:
+			status.State, status.Reason = StateBroken, err.Error()
+		case !slices.Equal(actual, expected):
+			status.State, status.Reason = StateOutdated, "文件头与当前公钥列表不一致"
+		case !ok:
+			status.State = StateUntracked
+		case entry.Recipients != v.Fingerprint:
+			status.State, status.Reason = StateOutdated, "加密时的公钥列表与当前不同"
+		}
+		statuses = append(statuses, status)
+	}
+
+	for name, entry := range v.manifest.Files {
+		if !seen[name] {
+			statuses = append(statuses, FileStatus{Name: name, State: StateMissing, Entry: entry, Reason: "文件已不存在"})
+		}
+	}
+
+	slices.SortFunc(statuses, func(a, b FileStatus) int { return strings.Compare(a.Name, b.Name) })
+	return statuses, nil
+}
+
+// stanzaKeys 返回加密给当前接收者集合时文件头中应有的 stanza 标识, 已排序
+func (v *Vault) stanzaKeys() ([]string, error) {
+	// 用随机的文件密钥包装一次, 只关心生成的 stanza 类型和参数
+	fileKey := make([]byte, 16)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, r := range v.Recipients {
+		stanzas, err := r.Wrap(fileKey)
+		if err != nil {
+			return nil, fmt.Errorf("无法使用公钥加密: %w", err)
+		}
+		for _, s := range stanzas {
+			keys = append(keys, stanzaKey(s.Type, s.Args))
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// fileStanzaKeys 读取文件头, 返回其中每个 stanza 的标识, 已排序
+func fileStanzaKeys(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	hdr, err := ageheader.Parse(ageheader.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(hdr.Stanzas))
+	for _, s := range hdr.Stanzas {
+		keys = append(keys, stanzaKey(s.Type, s.Args))
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// stanzaKey stanza 的标识
+// SSH 类型的第一个参数是公钥的指纹, 可以区分不同的接收者;
+// X25519 的参数是每次随机生成的临时公钥, 只能比较类型和数量
+func stanzaKey(typ string, args []string) string {
+	if strings.HasPrefix(typ, "ssh-") && len(args) > 0 {
+		return typ + " " + args[0]
+	}
+	return typ
+}
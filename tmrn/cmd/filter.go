@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"tmrn/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// addFilterFlags 添加选择文件的选项, 默认命令和 sub 子命令共用
+func addFilterFlags(cmd *cobra.Command, runner *cli.Runner) {
+	cmd.Flags().StringSliceVarP(&runner.FileExts, "extension", "e", nil, "File extension to process (repeatable or comma-separated)")
+	cmd.Flags().StringSliceVar(&runner.Types, "type", nil, "File type detected from content: image, video, audio or doc (repeatable; combined with --extension as either-or)")
+	cmd.Flags().StringArrayVar(&runner.Includes, "include", nil, "Only process files whose name matches this glob (repeatable, case-insensitive)")
+	cmd.Flags().StringArrayVar(&runner.Excludes, "exclude", nil, "Skip files whose name matches this glob (repeatable, case-insensitive)")
+}
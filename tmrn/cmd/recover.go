@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"tmrn/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newRecoverCmd 创建 recover 子命令, 恢复中断后残留的临时文件
+func newRecoverCmd() *cobra.Command {
+	runner := cli.NewRecoverRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "recover [dir]",
+		Short:        "Restore files left with a .tmrn-tmp suffix after an interrupted rename",
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.DirPath = "."
+			if len(args) == 1 {
+				runner.DirPath = args[0]
+			}
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&runner.Recursive, "recursive", "R", false, "Look for leftovers in every subdirectory")
+	cmd.Flags().BoolVar(&runner.Hidden, "hidden", false, "Include hidden directories with --recursive")
+	cmd.Flags().BoolVar(&runner.DryRun, "dry-run", false, "Print the recovery plan without touching any file")
+
+	return cmd
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"tmrn/internal/cli"
@@ -24,7 +25,7 @@ func newRootCmd() *cobra.Command {
 
 	var cmd = &cobra.Command{
 		Use:          "tmrn [dir]",
-		Short:        "Batch rename files based on modification time",
+		Short:        "Batch rename files based on modification time or capture date",
 		SilenceUsage: true,
 		Args:         cobra.MaximumNArgs(1), // 最多 1 个位置参数
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -35,6 +36,11 @@ func newRootCmd() *cobra.Command {
 				runner.DirPath = args[0]
 			}
 
+			// unshuffle 子命令也使用 --legacy, 这里单独检查它对 --shuffle 的依赖
+			if runner.Legacy && !runner.ShuffleMode {
+				return errors.New("--legacy 需要与 --shuffle 一起使用, 只去掉旧前缀请使用 unshuffle --legacy")
+			}
+
 			// 校验选项参数
 			if err := runner.Validate(); err != nil {
 				return err
@@ -44,14 +50,31 @@ func newRootCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().BoolVarP(&runner.ReverseSort, "reverse", "r", false, "Sort from newest to oldest")
-	cmd.Flags().BoolVarP(&runner.ShuffleMode, "shuffle", "s", false, "Randomly shuffle filenames by adding a 4-digit random prefix")
-	cmd.Flags().StringVarP(&runner.FileExt, "extension", "e", "", "Specify the file extension to process")
+	cmd.Flags().BoolVarP(&runner.ReverseSort, "reverse", "r", false, "Reverse the sort order (e.g. newest first)")
+	cmd.Flags().BoolVarP(&runner.ShuffleMode, "shuffle", "s", false, "Randomly shuffle filenames by adding a 4-letter random prefix such as 'Abcd~' (an existing one is replaced)")
+	cmd.Flags().StringVar(&runner.ShuffleStyle, "shuffle-mode", runner.ShuffleStyle, "With --shuffle: 'prefix' adds a random prefix, 'number' renumbers files in random order")
+	cmd.Flags().Uint64Var(&runner.Seed, "seed", 0, "Random seed for --shuffle, to reproduce a previous result (0 picks one)")
+	cmd.Flags().BoolVar(&runner.Legacy, "legacy", false, "With --shuffle: also replace 'Abcd_' prefixes added by older versions (a name like 'user_guide.pdf' is treated as prefixed too)")
+	addFilterFlags(cmd, runner)
 	cmd.Flags().StringVarP(&runner.NamePrefix, "name", "n", "", "Specify a filename prefix (e.g. 'video' -> 'video_001.ext')")
+	cmd.Flags().StringVarP(&runner.Template, "template", "t", "", "Filename template, e.g. '{date}_{n:03}' (tokens: n, date, time, name, ext, parent, exif.camera, hash; the extension is kept unless {ext} is used)")
+	cmd.Flags().StringVar(&runner.SortBy, "sort-by", runner.SortBy, "Sort key: exif, mtime, ctime, btime, name or size (exif falls back to mtime)")
+	cmd.Flags().BoolVarP(&runner.Recursive, "recursive", "R", false, "Rename files in every subdirectory, confirming folder by folder")
+	cmd.Flags().StringVar(&runner.Numbering, "numbering", runner.Numbering, "Numbering with --recursive: 'folder' restarts in each folder, 'global' runs across the tree")
+	cmd.Flags().BoolVar(&runner.Hidden, "hidden", false, "Include hidden directories with --recursive")
+	cmd.Flags().StringVar(&runner.OnConflict, "on-conflict", runner.OnConflict, "When a target name is taken by a file outside the batch: 'abort', 'free' (pick a free name) or 'include' (rename that file too)")
+	cmd.Flags().BoolVarP(&runner.Interactive, "interactive", "i", false, "Review the plan in a terminal UI: reorder, exclude and rename files before confirming")
+	cmd.Flags().BoolVar(&runner.DryRun, "dry-run", false, "Print the rename plan without touching any file")
 
 	// 互斥设置
 	cmd.MarkFlagsMutuallyExclusive("shuffle", "reverse")
-	cmd.MarkFlagsMutuallyExclusive("shuffle", "name")
+	cmd.MarkFlagsMutuallyExclusive("shuffle", "sort-by")
+	cmd.MarkFlagsMutuallyExclusive("name", "template")
+
+	cmd.AddCommand(newSubCmd())
+	cmd.AddCommand(newUnshuffleCmd())
+	cmd.AddCommand(newRecoverCmd())
+	cmd.AddCommand(newUndoCmd())
 
 	return cmd
 }
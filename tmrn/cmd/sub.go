@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"tmrn/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newSubCmd 创建 sub 子命令, 按正则表达式批量替换文件名
+func newSubCmd() *cobra.Command {
+	runner := cli.NewSubRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "sub <pattern> <replacement> [dir]",
+		Short:        "Rename files by regular expression substitution",
+		SilenceUsage: true,
+		Args:         cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.Pattern = args[0]
+			runner.Replacement = args[1]
+			runner.DirPath = "."
+			if len(args) == 3 {
+				runner.DirPath = args[2]
+			}
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&runner.IgnoreCase, "ignore-case", "i", false, "Match the pattern case-insensitively")
+	cmd.Flags().BoolVarP(&runner.Literal, "literal", "F", false, "Treat pattern and replacement as plain text")
+	cmd.Flags().BoolVar(&runner.WithExt, "with-ext", false, "Apply to the extension as well (default: name without extension)")
+	cmd.Flags().StringVar(&runner.Case, "case", "", "Convert the result to 'lower', 'upper' or 'title' case")
+	cmd.Flags().BoolVar(&runner.Translit, "translit", false, "Transliterate accented and full-width characters to ASCII")
+	addFilterFlags(cmd, &runner.Runner)
+	cmd.Flags().BoolVarP(&runner.Recursive, "recursive", "R", false, "Rename files in every subdirectory")
+	cmd.Flags().BoolVar(&runner.Hidden, "hidden", false, "Include hidden directories with --recursive")
+	cmd.Flags().StringVar(&runner.OnConflict, "on-conflict", runner.OnConflict, "When a target name is taken by a file outside the batch: 'abort', 'free' (pick a free name) or 'include' (rename that file too)")
+	cmd.Flags().BoolVar(&runner.DryRun, "dry-run", false, "Print the rename plan without touching any file")
+
+	return cmd
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"tmrn/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newUndoCmd 创建 undo 子命令, 撤销之前的重命名批次
+func newUndoCmd() *cobra.Command {
+	runner := cli.NewUndoRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "undo",
+		Short:        "Restore the original names of a previous rename batch",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().IntVar(&runner.ID, "id", 0, "Batch to undo (default: the most recent batch not yet undone)")
+	cmd.Flags().BoolVarP(&runner.List, "list", "l", false, "List recorded batches")
+
+	return cmd
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"tmrn/internal/cli"
+
+	"github.com/spf13/cobra"
+)
+
+// newUnshuffleCmd 创建 unshuffle 子命令, 去掉随机模式添加的前缀
+func newUnshuffleCmd() *cobra.Command {
+	runner := cli.NewUnshuffleRunner()
+
+	var cmd = &cobra.Command{
+		Use:          "unshuffle [dir]",
+		Short:        "Strip the random prefixes added by --shuffle",
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runner.DirPath = "."
+			if len(args) == 1 {
+				runner.DirPath = args[0]
+			}
+
+			if err := runner.Validate(); err != nil {
+				return err
+			}
+
+			return runner.Run()
+		},
+	}
+
+	addFilterFlags(cmd, &runner.Runner)
+	cmd.Flags().BoolVar(&runner.Legacy, "legacy", false, "Also strip 'Abcd_' prefixes added by older versions (a name like 'user_guide.pdf' loses 'user_' too, check with --dry-run)")
+	cmd.Flags().BoolVarP(&runner.Recursive, "recursive", "R", false, "Strip prefixes in every subdirectory")
+	cmd.Flags().BoolVar(&runner.Hidden, "hidden", false, "Include hidden directories with --recursive")
+	cmd.Flags().StringVar(&runner.OnConflict, "on-conflict", runner.OnConflict, "When a restored name is taken by a file outside the batch: 'abort', 'free' (pick a free name) or 'include' (rename that file too)")
+	cmd.Flags().BoolVar(&runner.DryRun, "dry-run", false, "Print the rename plan without touching any file")
+
+	return cmd
+}
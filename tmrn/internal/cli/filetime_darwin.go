@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// changeTime 返回文件的状态改变时间 (ctime)
+func changeTime(_ string, info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Ctimespec.Unix()), true
+}
+
+// birthTime 返回文件的创建时间
+func birthTime(_ string, info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Birthtimespec.Unix()), true
+}
+
+// fileID 返回文件所在的设备号和 inode 号, 重命名不会改变它们
+func fileID(info os.FileInfo) (dev, ino uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(st.Dev), st.Ino, true
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !windows
+
+package cli
+
+import (
+	"os"
+	"time"
+)
+
+// changeTime 当前平台不支持读取 ctime
+func changeTime(_ string, _ os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// birthTime 当前平台不支持读取创建时间
+func birthTime(_ string, _ os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// fileID 当前平台不支持读取 inode 号
+func fileID(_ os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
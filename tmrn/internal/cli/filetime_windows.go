@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// changeTime Windows 没有 ctime, 始终返回 false
+func changeTime(_ string, _ os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// birthTime 返回文件的创建时间
+func birthTime(_ string, info os.FileInfo) (time.Time, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
+
+// fileID os.FileInfo 中没有文件编号, 始终返回 false
+func fileID(_ os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"tmrn/internal/media"
+)
+
+// validateFilters 规范化扩展名, 校验类型名称和通配符
+func (r *Runner) validateFilters() error {
+	// 确保文件格式以点号开头, 忽略空值
+	exts := make([]string, 0, len(r.FileExts))
+	for _, ext := range r.FileExts {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext != "" {
+			exts = append(exts, "."+ext)
+		}
+	}
+	r.FileExts = exts
+
+	r.kinds = r.kinds[:0]
+	for _, t := range r.Types {
+		kind := media.Kind(strings.ToLower(strings.TrimSpace(t)))
+		if !slices.Contains(media.Kinds, kind) {
+			return fmt.Errorf("不支持的文件类型 -> '%s' (可选: image, video, audio, doc)", t)
+		}
+		r.kinds = append(r.kinds, kind)
+	}
+
+	for _, pattern := range slices.Concat(r.Includes, r.Excludes) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的通配符 -> '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// selected 判断文件是否符合筛选条件
+// 扩展名和类型满足其一即可, 之后还需匹配任一 --include 且不匹配任何 --exclude, 通配符不区分大小写
+func (r *Runner) selected(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	if slices.ContainsFunc(r.Excludes, func(p string) bool { return globMatch(p, name) }) {
+		return false
+	}
+	if len(r.Includes) > 0 && !slices.ContainsFunc(r.Includes, func(p string) bool { return globMatch(p, name) }) {
+		return false
+	}
+
+	if len(r.FileExts) == 0 && len(r.kinds) == 0 {
+		return true
+	}
+	ext := filepath.Ext(name)
+	if slices.ContainsFunc(r.FileExts, func(e string) bool { return strings.EqualFold(ext, e) }) {
+		return true
+	}
+	if len(r.kinds) > 0 {
+		// 按内容判断类型, 不依赖扩展名
+		kind, err := media.DetectKind(path)
+		return err == nil && slices.Contains(r.kinds, kind)
+	}
+	return false
+}
+
+// globMatch 不区分大小写地匹配文件名, name 已转换为小写
+func globMatch(pattern, name string) bool {
+	ok, _ := filepath.Match(strings.ToLower(pattern), name)
+	return ok
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,18 +11,21 @@ import (
 
 // fileInfo 单个文件的信息
 type fileInfo struct {
-	path    string
-	modTime time.Time
-	ext     string
+	path     string
+	sortTime time.Time // 排序使用的时间, 由 --sort-by 决定
+	size     int64
+	ext      string
 }
 
-func (r *Runner) findFiles() ([]fileInfo, error) {
+// findFiles 查找 dir 中匹配的文件, 同时返回无法获取指定时间而回退到修改时间的文件数
+func (r *Runner) findFiles(dir string) ([]fileInfo, int, error) {
 	var files []fileInfo
+	fallbacks := 0
 
 	// 使用 os.ReadDir 只读取目录的第一层条目，不进行递归
-	entries, err := os.ReadDir(r.DirPath)
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("读取目录失败 %s: %w", r.DirPath, err)
+		return nil, 0, fmt.Errorf("读取目录失败 %s: %w", dir, err)
 	}
 
 	for _, d := range entries {
@@ -30,10 +34,15 @@ func (r *Runner) findFiles() ([]fileInfo, error) {
 		}
 
 		name := d.Name()
-		ext := filepath.Ext(name)
+		path := filepath.Join(dir, name) // 手动拼接完整路径
 
-		// 如果指定了文件格式，则只处理匹配的文件
-		if r.FileExt != "" && !strings.EqualFold(ext, r.FileExt) {
+		// 上次中断留下的临时文件由 recover 子命令处理
+		if strings.HasSuffix(name, tmpSuffix) {
+			continue
+		}
+
+		// 只处理符合扩展名、类型和通配符条件的文件
+		if !r.selected(path) {
 			continue
 		}
 
@@ -43,12 +52,90 @@ func (r *Runner) findFiles() ([]fileInfo, error) {
 			continue
 		}
 
-		files = append(files, fileInfo{
-			path:    filepath.Join(r.DirPath, name), // 手动拼接完整路径
-			modTime: info.ModTime(),
-			ext:     ext,
-		})
+		file, ok := r.newFileInfo(path, info)
+		if !ok {
+			fallbacks++
+		}
+		files = append(files, file)
+	}
+
+	return files, fallbacks, nil
+}
+
+// newFileInfo 根据文件状态生成文件信息, 无法获取 --sort-by 指定的时间时第二个返回值为 false
+func (r *Runner) newFileInfo(path string, info os.FileInfo) (fileInfo, bool) {
+	sortTime, ok := r.fileTime(path, info)
+	return fileInfo{
+		path:     path,
+		sortTime: sortTime,
+		size:     info.Size(),
+		ext:      filepath.Ext(path),
+	}, ok
+}
+
+// findLeftovers 查找上次中断后残留的临时文件
+func findLeftovers(dirs []string) ([]string, error) {
+	var leftovers []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("读取目录失败 %s: %w", dir, err)
+		}
+		for _, d := range entries {
+			if !d.IsDir() && strings.HasSuffix(d.Name(), tmpSuffix) {
+				leftovers = append(leftovers, filepath.Join(dir, d.Name()))
+			}
+		}
+	}
+	return leftovers, nil
+}
+
+// checkLeftovers 存在残留的临时文件时拒绝继续, 避免在未完成的批次上叠加新的重命名
+func checkLeftovers(dirs []string) error {
+	leftovers, err := findLeftovers(dirs)
+	if err != nil {
+		return err
+	}
+	if len(leftovers) > 0 {
+		return fmt.Errorf("发现 %d 个上次中断后残留的临时文件 (*%s), 请先运行 'tmrn recover' 恢复", len(leftovers), tmpSuffix)
+	}
+	return nil
+}
+
+// findDirs 返回需要处理的目录, 未开启递归时只有目标目录本身
+// 递归时按字典序遍历, 默认跳过隐藏目录, 不跟随符号链接
+func (r *Runner) findDirs() ([]string, error) {
+	if !r.Recursive {
+		return []string{r.DirPath}, nil
 	}
 
-	return files, nil
+	var dirs []string
+	err := filepath.WalkDir(r.DirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == r.DirPath {
+				return err
+			}
+			warnColor.Fprintf(os.Stderr, "注意: 无法读取目录 %s: %v\n", path, err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != r.DirPath && !r.Hidden && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历目录失败 %s: %w", r.DirPath, err)
+	}
+	return dirs, nil
+}
+
+// warnFallbacks 提示回退到修改时间排序的文件数
+func (r *Runner) warnFallbacks(fallbacks int) {
+	if fallbacks > 0 {
+		warnColor.Fprintf(os.Stderr, "注意: %d 个文件没有%s, 已改用修改时间排序\n", fallbacks, sortTimeLabels[r.SortBy])
+	}
 }
@@ -0,0 +1,439 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// 交互界面底部的按键说明
+const (
+	listHelp = "↑↓ 选择  Shift+↑↓/K J 移动  空格 排除  e 改名  r 还原  回车 确认  q 退出"
+	editHelp = "回车 保存  Esc 取消  ←→ 移动光标  Ctrl+U 清空"
+)
+
+// planEditor 交互模式的状态: 当前顺序、排除的文件和手动修改的名称
+type planEditor struct {
+	r        *Runner
+	files    []fileInfo        // 当前顺序, 编号按这个顺序生成
+	excluded map[string]bool   // 排除的文件保持原名, 也不占用编号
+	edits    map[string]string // 手动修改的新名称, 代替模板生成的名称
+
+	plan      []renameOp
+	targets   map[string]string // 原路径 -> 新名称
+	conflicts map[string]string // 原路径 -> 冲突原因
+
+	cursor, offset, height int
+	message                string
+	messageColor           *color.Color
+
+	editing  bool
+	input    []rune
+	inputPos int
+}
+
+// runInteractive 在终端界面中调整顺序、排除文件和修改名称, 确认后与其他模式一样分两阶段执行
+func (r *Runner) runInteractive(files []fileInfo) error {
+	if r.ShuffleMode {
+		shuffleFiles(files, r.newRand())
+	} else {
+		r.sortFiles(files)
+	}
+
+	e := &planEditor{
+		r:        r,
+		files:    files,
+		excluded: make(map[string]bool),
+		edits:    make(map[string]string),
+	}
+	if err := e.refresh(); err != nil {
+		return fmt.Errorf("生成重命名计划时出错: %w", err)
+	}
+
+	s, err := openScreen()
+	if err != nil {
+		return err
+	}
+	confirmed, err := e.run(s)
+	s.close()
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		warnColor.Printf("操作已取消\n")
+		return nil
+	}
+
+	if r.DryRun {
+		printPlan(e.plan, nil, 0)
+		warnColor.Printf("预演模式, 未修改任何文件\n")
+		return nil
+	}
+	renames, _ := countPlan(e.plan)
+	return r.executeBatch(e.plan, renames)
+}
+
+// run 处理按键直到用户确认或退出, 返回是否确认
+func (e *planEditor) run(s *screen) (bool, error) {
+	for {
+		e.draw(s)
+		key, err := readKey()
+		if err != nil {
+			return false, err
+		}
+		e.message = ""
+
+		if e.editing {
+			if err := e.handleInput(key); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		switch key {
+		case "up", "k":
+			e.moveCursor(-1)
+		case "down", "j":
+			e.moveCursor(1)
+		case "pgup":
+			e.moveCursor(-e.listHeight())
+		case "pgdn":
+			e.moveCursor(e.listHeight())
+		case "home", "g":
+			e.cursor = 0
+		case "end", "G":
+			e.cursor = len(e.files) - 1
+		case "shift-up", "K":
+			err = e.moveFile(-1)
+		case "shift-down", "J":
+			err = e.moveFile(1)
+		case " ", "x":
+			path := e.files[e.cursor].path
+			e.excluded[path] = !e.excluded[path]
+			err = e.refresh()
+		case "e":
+			e.startEditing()
+		case "r":
+			if _, ok := e.edits[e.files[e.cursor].path]; ok {
+				delete(e.edits, e.files[e.cursor].path)
+				err = e.refresh()
+			}
+		case "enter":
+			if e.confirmable() {
+				return true, nil
+			}
+		case "q", "esc", "ctrl-c":
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// refresh 按当前顺序重新生成计划, 排除的文件不参与编号
+func (e *planEditor) refresh() error {
+	var included []fileInfo
+	for _, file := range e.files {
+		if !e.excluded[file.path] {
+			included = append(included, file)
+		}
+	}
+
+	plan, err := e.r.planWithPolicy(included, e.excluded, e.build)
+	if err != nil {
+		return err
+	}
+	e.plan = plan
+
+	e.targets = make(map[string]string, len(plan))
+	for _, op := range plan {
+		e.targets[op.originalPath] = filepath.Base(op.finalPath)
+	}
+	e.conflicts = make(map[string]string)
+	for i, reason := range findConflicts(plan) {
+		if e.excluded[plan[i].finalPath] {
+			reason = "目标名称被已排除的文件占用"
+		}
+		e.conflicts[plan[i].originalPath] = reason
+	}
+	return nil
+}
+
+// build 按模板编号后用手动修改的名称替换
+func (e *planEditor) build(files []fileInfo) ([]renameOp, error) {
+	plan, err := e.r.planNumbered(files)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range plan {
+		if name, ok := e.edits[op.originalPath]; ok {
+			plan[i] = newRenameOp(op.originalPath, filepath.Join(filepath.Dir(op.originalPath), name))
+		}
+	}
+	return plan, nil
+}
+
+// confirmable 检查计划能否执行, 不能时在底部显示原因
+func (e *planEditor) confirmable() bool {
+	renames, _ := countPlan(e.plan)
+	switch {
+	case len(e.conflicts) > 0:
+		e.setMessage(errorColor, "还有 %d 个冲突, 请修改名称、调整顺序或排除文件后再确认", len(e.conflicts))
+	case renames == 0:
+		e.setMessage(warnColor, "所有文件的名称都无需修改, 按 q 退出")
+	default:
+		return true
+	}
+	return false
+}
+
+// moveCursor 移动光标, 超出范围时停在第一个或最后一个文件
+func (e *planEditor) moveCursor(delta int) {
+	e.cursor = min(max(e.cursor+delta, 0), len(e.files)-1)
+}
+
+// moveFile 将光标处的文件与相邻的文件交换位置
+func (e *planEditor) moveFile(delta int) error {
+	j := e.cursor + delta
+	if j < 0 || j >= len(e.files) {
+		return nil
+	}
+	e.files[e.cursor], e.files[j] = e.files[j], e.files[e.cursor]
+	e.cursor = j
+	return e.refresh()
+}
+
+// startEditing 以当前的新名称作为初始内容开始编辑
+func (e *planEditor) startEditing() {
+	path := e.files[e.cursor].path
+	if e.excluded[path] {
+		e.setMessage(warnColor, "已排除的文件保持原名, 按空格恢复后才能改名")
+		return
+	}
+	e.editing = true
+	e.input = []rune(e.targets[path])
+	e.inputPos = len(e.input)
+}
+
+// handleInput 处理编辑名称时的按键
+func (e *planEditor) handleInput(key string) error {
+	switch key {
+	case "enter":
+		name := strings.TrimSpace(string(e.input))
+		if err := validateName(name); err != nil {
+			e.setMessage(errorColor, "新名称无效: %v", err)
+			return nil
+		}
+		e.edits[e.files[e.cursor].path] = name
+		e.editing = false
+		return e.refresh()
+	case "esc", "ctrl-c":
+		e.editing = false
+	case "left":
+		e.inputPos = max(e.inputPos-1, 0)
+	case "right":
+		e.inputPos = min(e.inputPos+1, len(e.input))
+	case "home":
+		e.inputPos = 0
+	case "end":
+		e.inputPos = len(e.input)
+	case "backspace":
+		if e.inputPos > 0 {
+			e.input = append(e.input[:e.inputPos-1], e.input[e.inputPos:]...)
+			e.inputPos--
+		}
+	case "delete":
+		if e.inputPos < len(e.input) {
+			e.input = append(e.input[:e.inputPos], e.input[e.inputPos+1:]...)
+		}
+	case "ctrl-u":
+		e.input, e.inputPos = nil, 0
+	default:
+		// 其余按键名称都不止一个字符, 单个可打印字符才插入
+		r, size := utf8.DecodeRuneInString(key)
+		if size == len(key) && unicode.IsPrint(r) {
+			e.input = append(e.input[:e.inputPos], append([]rune{r}, e.input[e.inputPos:]...)...)
+			e.inputPos++
+		}
+	}
+	return nil
+}
+
+// setMessage 在底部显示一条提示, 下一次按键后消失
+func (e *planEditor) setMessage(c *color.Color, format string, a ...any) {
+	e.messageColor = c
+	e.message = fmt.Sprintf(format, a...)
+}
+
+// listHeight 列表区域的行数, 顶部两行标题, 底部两行提示
+func (e *planEditor) listHeight() int {
+	return max(e.height-4, 1)
+}
+
+// draw 绘制整个界面
+func (e *planEditor) draw(s *screen) {
+	width, height := s.size()
+	width-- // 不写最后一列, 避免部分终端自动换行
+	e.height = height
+	rows := e.listHeight()
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	}
+	if e.cursor >= e.offset+rows {
+		e.offset = e.cursor - rows + 1
+	}
+
+	// 1. 标题和表头
+	renames, skipped := countPlan(e.plan)
+	excluded := 0
+	for _, file := range e.files {
+		if e.excluded[file.path] {
+			excluded++
+		}
+	}
+	title := fmt.Sprintf("tmrn 交互模式  共 %d 个文件: 重命名 %d, 不变 %d, 排除 %d", len(e.files), renames, skipped, excluded)
+	if len(e.conflicts) > 0 {
+		title += fmt.Sprintf(", 冲突 %d", len(e.conflicts))
+	}
+	lines := []string{noticeColor.Sprint(fitText(title, width))}
+
+	numWidth := len(strconv.Itoa(len(e.files)))
+	showMeta := width >= 90
+	fixed := 2 + numWidth + 3 + 4 // 光标、序号、状态标记和箭头
+	if showMeta {
+		fixed += 29 // 时间和大小
+	}
+	nameWidth := max((width-fixed)/2, 8)
+
+	header := strings.Repeat(" ", 2+numWidth+3) + fitText("原名称", nameWidth)
+	if showMeta {
+		header += "  " + fitText(sortTimeHeader(e.r.SortBy), 16) + "  " + strings.Repeat(" ", 9-textWidth("大小")) + "大小" + "  "
+	} else {
+		header += "  "
+	}
+	header += "  " + "新名称"
+	lines = append(lines, skipColor.Sprint(fitText(header, width)))
+
+	// 2. 文件列表
+	for i := e.offset; i < e.offset+rows; i++ {
+		if i >= len(e.files) {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, e.drawRow(i, numWidth, nameWidth, showMeta, width))
+	}
+
+	// 3. 底部提示, 编辑时输入行占用提示行, 校验错误等消息显示在按键说明的位置
+	help := skipColor.Sprint(fitText(listHelp, width))
+	switch {
+	case e.editing:
+		lines = append(lines, e.drawInput(width))
+		if e.message != "" {
+			help = e.messageColor.Sprint(fitText(e.message, width))
+		} else {
+			help = skipColor.Sprint(fitText(editHelp, width))
+		}
+	case e.message != "":
+		lines = append(lines, e.messageColor.Sprint(fitText(e.message, width)))
+	default:
+		lines = append(lines, e.rowHint(width))
+	}
+	lines = append(lines, help)
+
+	s.draw(lines)
+}
+
+// drawRow 绘制一个文件, 状态标记: "-" 排除, "=" 名称不变, "*" 手动改名, "!" 冲突
+func (e *planEditor) drawRow(i, numWidth, nameWidth int, showMeta bool, width int) string {
+	file := e.files[i]
+	target, planned := e.targets[file.path]
+	_, edited := e.edits[file.path]
+
+	mark, attrs := " ", []color.Attribute(nil)
+	switch {
+	case e.excluded[file.path] && !planned:
+		mark, attrs, target = "-", []color.Attribute{color.Faint}, "(已排除, 保持原名)"
+	case e.conflicts[file.path] != "":
+		mark, attrs = "!", []color.Attribute{color.FgRed}
+	case target == filepath.Base(file.path):
+		mark, attrs = "=", []color.Attribute{color.Faint}
+	case edited:
+		mark, attrs = "*", []color.Attribute{color.FgYellow}
+	}
+
+	cursor := "  "
+	if i == e.cursor {
+		cursor = "> "
+		attrs = append(attrs, color.ReverseVideo)
+	}
+
+	row := fmt.Sprintf("%s%*d %s ", cursor, numWidth, i+1, mark) + fitText(filepath.Base(file.path), nameWidth)
+	if showMeta {
+		row += "  " + fitText(file.sortTime.Format("2006-01-02 15:04"), 16) + "  " + fmt.Sprintf("%9s", formatSize(file.size))
+	}
+	row += " -> " + target
+	return color.New(attrs...).Sprint(fitText(row, width))
+}
+
+// rowHint 光标所在文件的补充说明, 如冲突原因
+func (e *planEditor) rowHint(width int) string {
+	path := e.files[e.cursor].path
+	if reason := e.conflicts[path]; reason != "" {
+		return errorColor.Sprint(fitText("冲突: "+reason, width))
+	}
+	if _, ok := e.edits[path]; ok {
+		return noticeColor.Sprint(fitText("名称已手动修改, 按 r 还原为模板生成的名称", width))
+	}
+	return ""
+}
+
+// drawInput 绘制编辑中的名称, 光标处反色显示, 过长时只显示光标附近的部分
+func (e *planEditor) drawInput(width int) string {
+	const label = "新名称: "
+	before := string(e.input[:e.inputPos])
+	at, after := " ", ""
+	if e.inputPos < len(e.input) {
+		at, after = string(e.input[e.inputPos]), string(e.input[e.inputPos+1:])
+	}
+
+	room := width - textWidth(label) - textWidth(at) - 1
+	if textWidth(before) > room {
+		// 去掉开头的字符直到放得下, 并留出 "…" 的位置
+		for textWidth(before) > room-1 && before != "" {
+			_, size := utf8.DecodeRuneInString(before)
+			before = before[size:]
+		}
+		before = "…" + before
+	}
+	after = strings.TrimRight(fitText(after, room-textWidth(before)), " ")
+
+	return label + before + escReverse + at + escReset + after
+}
+
+// sortTimeHeader 时间一列的标题, 与 --sort-by 使用的时间一致
+func sortTimeHeader(sortBy string) string {
+	if label, ok := sortTimeLabels[sortBy]; ok {
+		return label
+	}
+	return "修改时间"
+}
+
+// formatSize 以 B, KB, MB 等单位显示文件大小
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
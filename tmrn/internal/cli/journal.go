@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalEntry 一个文件在批次中的原名称和最终名称
+// 同时记录文件的大小、修改时间和 inode 号 (重命名不会改变它们), 撤销前据此判断文件是否被修改或替换
+type journalEntry struct {
+	Original string    `json:"original"` // 原始绝对路径
+	Final    string    `json:"final"`    // 重命名后的绝对路径
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mtime"`
+	Dev      uint64    `json:"dev,omitempty"` // 不支持的平台上为 0
+	Ino      uint64    `json:"ino,omitempty"`
+}
+
+// journal 一次重命名批次的记录
+type journal struct {
+	ID      int            `json:"id"`
+	Time    time.Time      `json:"time"`
+	Dir     string         `json:"dir"`
+	Entries []journalEntry `json:"entries"`
+	Pending bool           `json:"pending,omitempty"` // 重命名开始前写入, 完成后清除; 进程中途退出时保留
+	Undone  *time.Time     `json:"undone,omitempty"`  // 撤销的时间, 未撤销时为空
+}
+
+// journalDir 返回日志目录: $XDG_STATE_HOME/tmrn, 未设置时为 ~/.local/state/tmrn
+func journalDir() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("无法获取用户主目录: %w", err)
+		}
+		stateHome = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateHome, "tmrn"), nil
+}
+
+// journalPath 返回批次日志的文件路径
+func journalPath(dir string, id int) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.json", id))
+}
+
+// createJournal 在重命名之前为计划写入新的批次日志, 标记为未完成, 名称不变的文件不会被记录
+// 重命名保留文件的大小、修改时间和 inode 号, 因此可以在重命名之前读取
+func createJournal(dirPath string, plan []renameOp) (*journal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	absDir, err := filepath.Abs(dirPath)
+	if err != nil {
+		absDir = dirPath
+	}
+	j := &journal{Time: time.Now(), Dir: absDir, Pending: true}
+	if err := j.addEntries(plan); err != nil {
+		return nil, err
+	}
+
+	ids, err := listJournals(dir)
+	if err != nil {
+		return nil, err
+	}
+	j.ID = 1
+	if len(ids) > 0 {
+		j.ID = ids[len(ids)-1] + 1
+	}
+
+	// O_EXCL 保证并发运行时不会覆盖其他批次的日志, 编号已被占用时顺延
+	for ; ; j.ID++ {
+		data, err := json.MarshalIndent(j, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("序列化日志失败: %w", err)
+		}
+
+		f, err := os.OpenFile(journalPath(dir, j.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("创建日志失败: %w", err)
+		}
+		defer f.Close()
+
+		if _, err := f.Write(data); err != nil {
+			os.Remove(f.Name())
+			return nil, fmt.Errorf("写入日志失败: %w", err)
+		}
+		return j, nil
+	}
+}
+
+// extend 在继续重命名之前把新的计划加入未完成的批次, 用于分多次确认的递归模式
+func (j *journal) extend(plan []renameOp) error {
+	dir, err := journalDir()
+	if err != nil {
+		return err
+	}
+	if err := j.addEntries(plan); err != nil {
+		return err
+	}
+	return j.save(dir)
+}
+
+// addEntries 为计划中名称改变的文件添加日志条目
+func (j *journal) addEntries(plan []renameOp) error {
+	for _, op := range plan {
+		if op.unchanged() {
+			continue
+		}
+		entry, err := newJournalEntry(op.originalPath, op.finalPath)
+		if err != nil {
+			return err
+		}
+		j.Entries = append(j.Entries, entry)
+	}
+	return nil
+}
+
+// finish 重命名结束后只保留成功的文件并清除未完成标记, 没有成功的文件时删除日志
+func (j *journal) finish(results []renameResult) error {
+	dir, err := journalDir()
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return os.Remove(journalPath(dir, j.ID))
+	}
+
+	done := make(map[string]bool, len(results))
+	for _, result := range results {
+		if original, err := filepath.Abs(result.originalPath); err == nil {
+			done[original] = true
+		}
+	}
+	j.Entries = slices.DeleteFunc(j.Entries, func(e journalEntry) bool { return !done[e.Original] })
+	j.Pending = false
+	return j.save(dir)
+}
+
+// newJournalEntry 根据原名称、最终名称和原文件当前的状态生成日志条目
+func newJournalEntry(originalPath, finalPath string) (journalEntry, error) {
+	original, err := filepath.Abs(originalPath)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	final, err := filepath.Abs(finalPath)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	info, err := os.Lstat(original)
+	if err != nil {
+		return journalEntry{}, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	entry := journalEntry{Original: original, Final: final, Size: info.Size(), ModTime: info.ModTime()}
+	entry.Dev, entry.Ino, _ = fileID(info)
+	return entry, nil
+}
+
+// matches 判断文件是否仍是批次中记录的那个文件, 记录时平台不提供 inode 号 (Ino 为 0) 则只比较大小和修改时间
+func (e journalEntry) matches(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() || info.Size() != e.Size || !info.ModTime().Equal(e.ModTime) {
+		return false
+	}
+	if e.Ino == 0 {
+		return true
+	}
+	dev, ino, ok := fileID(info)
+	return !ok || (dev == e.Dev && ino == e.Ino)
+}
+
+// listJournals 按编号升序返回所有批次的编号
+func listJournals(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	var ids []int
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if id, err := strconv.Atoi(name); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// loadJournal 读取指定编号的批次日志
+func loadJournal(dir string, id int) (*journal, error) {
+	data, err := os.ReadFile(journalPath(dir, id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("批次不存在 -> %d", id)
+		}
+		return nil, fmt.Errorf("读取日志失败: %w", err)
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("解析日志失败: %w", err)
+	}
+	return &j, nil
+}
+
+// markUndone 记录批次已被撤销
+func (j *journal) markUndone(dir string) error {
+	now := time.Now()
+	j.Undone = &now
+	return j.save(dir)
+}
+
+// save 覆盖写入批次日志, 先写临时文件再重命名, 中途退出时不会留下写了一半的日志
+func (j *journal) save(dir string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化日志失败: %w", err)
+	}
+
+	path := journalPath(dir, j.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入日志失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入日志失败: %w", err)
+	}
+	return nil
+}
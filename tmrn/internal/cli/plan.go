@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tmpSuffix 第一阶段重命名使用的临时后缀
+const tmpSuffix = ".tmrn-tmp"
+
+// 目标名称被批次之外的文件占用时的处理方式
+const (
+	conflictAbort   = "abort"   // 显示冲突, 不修改任何文件
+	conflictFree    = "free"    // 为冲突的文件另选一个空闲的名称
+	conflictInclude = "include" // 将占用目标名称的文件加入本次重命名
+)
+
+// conflictPolicies 所有支持的冲突处理方式
+var conflictPolicies = []string{conflictAbort, conflictFree, conflictInclude}
+
+// renameOp 单个文件的重命名计划
+type renameOp struct {
+	originalPath string
+	tmpPath      string
+	finalPath    string
+}
+
+// newRenameOp 根据原路径和目标路径创建重命名计划
+func newRenameOp(originalPath, finalPath string) renameOp {
+	return renameOp{
+		originalPath: originalPath,
+		tmpPath:      originalPath + tmpSuffix,
+		finalPath:    finalPath,
+	}
+}
+
+// unchanged 文件名不变, 执行时跳过
+func (op renameOp) unchanged() bool {
+	return op.originalPath == op.finalPath
+}
+
+// buildPlan 根据模式生成重命名计划
+func (r *Runner) buildPlan(files []fileInfo) ([]renameOp, error) {
+	if r.ShuffleMode {
+		// 随机模式, 每次都从同一个种子开始, 相同的文件得到相同的结果
+		rng := r.newRand()
+		if r.ShuffleStyle == shuffleNumber {
+			return r.planRandomNumbers(files, rng)
+		}
+		return r.planRandomPrefix(files, rng), nil
+	}
+	// 默认模式, 排序后按模板命名
+	return r.planSequence(files)
+}
+
+// planSequence 按 --sort-by 指定的方式排序, 再按文件名模板生成计划
+func (r *Runner) planSequence(files []fileInfo) ([]renameOp, error) {
+	r.sortFiles(files)
+	return r.planNumbered(files)
+}
+
+// planNumbered 按 files 的顺序编号, 再按文件名模板生成计划
+func (r *Runner) planNumbered(files []fileInfo) ([]renameOp, error) {
+	plan := make([]renameOp, len(files))
+	for i, file := range files {
+		finalName, err := r.template.render(i+1, len(files), file)
+		if err != nil {
+			return nil, err
+		}
+		plan[i] = newRenameOp(file.path, filepath.Join(filepath.Dir(file.path), finalName))
+	}
+
+	return plan, nil
+}
+
+// findConflicts 检查计划中的冲突, 返回操作下标到冲突原因的映射
+// 冲突包括: 多个文件的目标名称相同, 以及目标文件已存在且不在本次重命名的文件中
+func findConflicts(plan []renameOp) map[int]string {
+	conflicts := make(map[int]string)
+
+	targets := make(map[string]int, len(plan))
+	for i, op := range plan {
+		if prev, ok := targets[op.finalPath]; ok {
+			reason := fmt.Sprintf("与 %s 的目标名称相同", filepath.Base(plan[prev].originalPath))
+			conflicts[i] = reason
+			continue
+		}
+		targets[op.finalPath] = i
+	}
+
+	for _, i := range outsideConflicts(plan) {
+		if _, ok := conflicts[i]; !ok {
+			conflicts[i] = "将覆盖不在本次重命名中的已有文件"
+		}
+	}
+
+	return conflicts
+}
+
+// outsideConflicts 返回目标名称被批次之外的已有文件占用的操作下标
+func outsideConflicts(plan []renameOp) []int {
+	originals := make(map[string]bool, len(plan))
+	for _, op := range plan {
+		originals[op.originalPath] = true
+	}
+
+	var idx []int
+	for i, op := range plan {
+		if op.unchanged() || originals[op.finalPath] {
+			continue
+		}
+		if info, err := os.Lstat(op.finalPath); err == nil {
+			// 不区分大小写的文件系统上, 只改变大小写时目标就是文件本身
+			if orig, err := os.Lstat(op.originalPath); err == nil && os.SameFile(info, orig) {
+				continue
+			}
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// planWithPolicy 生成计划, 并按 --on-conflict 处理与批次之外文件的冲突
+// abort 时原样返回, 冲突由 findConflicts 显示; include 时把占用目标名称的文件加入后重新生成计划
+// excluded 中的文件是用户明确排除的, 不会被加入批次, 也不会为它另选名称, 目标名称被它占用时保留为冲突
+func (r *Runner) planWithPolicy(files []fileInfo, excluded map[string]bool, build func([]fileInfo) ([]renameOp, error)) ([]renameOp, error) {
+	included := make(map[string]bool, len(files))
+	for _, file := range files {
+		included[file.path] = true
+	}
+
+	for {
+		plan, err := build(files)
+		if err != nil {
+			return nil, err
+		}
+		var outside []int
+		for _, i := range outsideConflicts(plan) {
+			if !excluded[plan[i].finalPath] {
+				outside = append(outside, i)
+			}
+		}
+		if len(outside) == 0 {
+			return plan, nil
+		}
+
+		switch r.OnConflict {
+		case conflictFree:
+			pickFreeNames(plan, outside)
+			return plan, nil
+		case conflictInclude:
+			// 每轮至少加入一个新文件, 目录中的文件有限, 循环一定会结束
+			added := 0
+			for _, i := range outside {
+				path := plan[i].finalPath
+				info, err := os.Lstat(path)
+				if included[path] || err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+				file, _ := r.newFileInfo(path, info)
+				files = append(files, file)
+				included[path] = true
+				added++
+			}
+			if added == 0 {
+				return plan, nil
+			}
+		default:
+			return plan, nil
+		}
+	}
+}
+
+// pickFreeNames 为冲突的操作选择 "名称_1.后缀" 这样未被占用的名称
+func pickFreeNames(plan []renameOp, outside []int) {
+	taken := make(map[string]bool, len(plan))
+	for _, op := range plan {
+		taken[op.finalPath] = true
+	}
+
+	for _, i := range outside {
+		final := plan[i].finalPath
+		ext := filepath.Ext(final)
+		stem := strings.TrimSuffix(final, ext)
+		for n := 1; ; n++ {
+			candidate := fmt.Sprintf("%s_%d%s", stem, n, ext)
+			if taken[candidate] {
+				continue
+			}
+			if _, err := os.Lstat(candidate); err == nil {
+				continue
+			}
+			plan[i].finalPath = candidate
+			taken[candidate] = true
+			break
+		}
+	}
+}
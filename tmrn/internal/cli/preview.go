@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdin 所有交互共用同一个带缓冲的标准输入, 避免多处读取时丢失已缓冲的内容
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine 读取一行用户输入, 去掉首尾空白
+func readLine() string {
+	line, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// isTerminal 标准输入和输出是否都连接到终端
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd())) && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// planPageSize 返回分页显示计划时每页的行数, 不在终端中运行时返回 0 表示不分页
+func planPageSize() int {
+	if !isTerminal() {
+		return 0
+	}
+	_, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 20
+	}
+	// 留出分页提示和确认问题的位置
+	return max(rows-3, 5)
+}
+
+// printPlan 以 diff 风格输出重命名计划: "~" 重命名, "=" 名称不变 (跳过), "!" 冲突
+// pageSize 大于 0 时分页显示, 用户输入 q 则跳过剩余部分
+func printPlan(plan []renameOp, conflicts map[int]string, pageSize int) {
+	for i, op := range plan {
+		if pageSize > 0 && i > 0 && i%pageSize == 0 {
+			fmt.Printf("-- 已显示 %d/%d, 回车继续, q 跳过 --", i, len(plan))
+			if strings.EqualFold(readLine(), "q") {
+				break
+			}
+		}
+
+		oldName := filepath.Base(op.originalPath)
+		newName := filepath.Base(op.finalPath)
+		switch reason, conflict := conflicts[i]; {
+		case conflict:
+			errorColor.Printf("! %s -> %s  (%s)\n", oldName, newName, reason)
+		case op.unchanged():
+			skipColor.Printf("= %s  (名称不变, 跳过)\n", oldName)
+		default:
+			fmt.Printf("~ %s -> %s\n", oldName, newName)
+		}
+	}
+
+	renames, skipped := countPlan(plan)
+	fmt.Printf("\n计划: 重命名 %d 个, 跳过 %d 个", renames, skipped)
+	if len(conflicts) > 0 {
+		errorColor.Printf(", 冲突 %d 个", len(conflicts))
+	}
+	fmt.Println()
+}
+
+// countPlan 统计需要重命名和名称不变的文件数
+func countPlan(plan []renameOp) (renames, skipped int) {
+	for _, op := range plan {
+		if op.unchanged() {
+			skipped++
+		} else {
+			renames++
+		}
+	}
+	return renames, skipped
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RecoverRunner 存储 recover 子命令的选项参数, 目录相关的选项与默认命令共用
+type RecoverRunner struct {
+	Runner
+}
+
+// NewRecoverRunner 构造函数
+func NewRecoverRunner() *RecoverRunner {
+	return &RecoverRunner{Runner: *NewRunner()}
+}
+
+// Run 将上次中断后残留的临时文件恢复为重命名之前的名称
+// 第一阶段的临时名称是 "原名称.tmrn-tmp", 原名称已被占用时改用空闲的名称, 不会覆盖任何文件
+func (r *RecoverRunner) Run() error {
+	r.printHeader()
+
+	dirs, err := r.findDirs()
+	if err != nil {
+		return err
+	}
+	leftovers, err := findLeftovers(dirs)
+	if err != nil {
+		return err
+	}
+	if len(leftovers) == 0 {
+		warnColor.Printf("没有发现残留的临时文件\n")
+		return nil
+	}
+
+	plan := make([]renameOp, len(leftovers))
+	for i, path := range leftovers {
+		plan[i] = newRenameOp(path, strings.TrimSuffix(path, tmpSuffix))
+	}
+	if outside := outsideConflicts(plan); len(outside) > 0 {
+		pickFreeNames(plan, outside)
+		warnColor.Printf("注意: %d 个文件的原名称已被占用, 将改用空闲的名称\n\n", len(outside))
+	}
+
+	fmt.Printf("发现 %d 个残留的临时文件, 将恢复为重命名之前的名称:\n", len(leftovers))
+	return r.applyPlan(plan)
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 递归模式下的编号方式
+const (
+	numberingFolder = "folder" // 每个目录从 1 开始编号
+	numberingGlobal = "global" // 整个目录树统一排序和编号
+)
+
+// folderPlan 一个目录的重命名计划
+type folderPlan struct {
+	dir       string
+	plan      []renameOp
+	conflicts map[int]string
+}
+
+// runRecursive 递归处理目标目录及其所有子目录, 每个目录单独显示计划并确认
+func (r *Runner) runRecursive() error {
+	// 1. 查找所有目录中的文件
+	dirs, err := r.findDirs()
+	if err != nil {
+		return err
+	}
+	if err := checkLeftovers(dirs); err != nil {
+		return err
+	}
+
+	var groups [][]fileInfo
+	totalFallbacks := 0
+	for _, dir := range dirs {
+		files, fallbacks, err := r.findFiles(dir)
+		if err != nil {
+			warnColor.Printf("注意: %v\n", err)
+			continue
+		}
+		totalFallbacks += fallbacks
+		if len(files) > 0 {
+			groups = append(groups, files)
+		}
+	}
+	r.warnFallbacks(totalFallbacks)
+
+	if len(groups) == 0 {
+		warnColor.Printf("没有找到匹配的文件\n")
+		return nil
+	}
+
+	// 2. 生成每个目录的重命名计划
+	plans, err := r.planFolders(groups)
+	if err != nil {
+		return fmt.Errorf("生成重命名计划时出错: %w", err)
+	}
+
+	renames, skipped, conflicts := 0, 0, 0
+	for _, fp := range plans {
+		n, s := countPlan(fp.plan)
+		renames, skipped, conflicts = renames+n, skipped+s, conflicts+len(fp.conflicts)
+	}
+	summary := fmt.Sprintf("共 %d 个目录: 重命名 %d 个, 跳过 %d 个", len(plans), renames, skipped)
+
+	// 3. 预演模式显示所有目录的计划
+	if r.DryRun {
+		for i, fp := range plans {
+			r.printFolderPlan(i, len(plans), fp, 0)
+		}
+		fmt.Println(summary)
+		warnColor.Printf("预演模式, 未修改任何文件\n")
+		return nil
+	}
+
+	// 任何目录存在冲突时都不执行, 只显示有冲突的目录
+	if conflicts > 0 {
+		for i, fp := range plans {
+			if len(fp.conflicts) > 0 {
+				r.printFolderPlan(i, len(plans), fp, planPageSize())
+			}
+		}
+		return conflictError(conflicts)
+	}
+	if renames == 0 {
+		warnColor.Printf("所有文件的名称都无需修改\n")
+		return nil
+	}
+	fmt.Printf("%s\n\n", summary)
+
+	// 4. 逐个目录确认并重命名
+	var results []renameResult
+	var batch *journal
+	confirmed, all := 0, false
+	pageSize := planPageSize()
+
+folders:
+	for i, fp := range plans {
+		if all {
+			pageSize = 0
+		}
+		r.printFolderPlan(i, len(plans), fp, pageSize)
+
+		n, _ := countPlan(fp.plan)
+		if n == 0 {
+			skipColor.Printf("该目录中的文件名称都无需修改\n\n")
+			continue
+		}
+		if !all {
+			switch askFolderConfirmation(n) {
+			case "y":
+			case "a":
+				all = true
+			case "q":
+				warnColor.Printf("操作已取消, 剩余目录未处理\n")
+				break folders
+			default:
+				warnColor.Printf("已跳过该目录\n\n")
+				continue
+			}
+		}
+
+		// 整个目录树记录为同一个批次, 每个目录重命名之前先把它的计划写入日志
+		var err error
+		if batch == nil {
+			batch, err = createJournal(r.DirPath, fp.plan)
+		} else {
+			err = batch.extend(fp.plan)
+		}
+		if err != nil {
+			errorColor.Printf("无法记录批次日志, 已跳过该目录: %v\n\n", err)
+			continue
+		}
+
+		folderResults, err := executePlan(fp.plan)
+		if err != nil {
+			errorColor.Printf("重命名文件时出错: %v\n\n", err)
+			continue
+		}
+		for _, result := range folderResults {
+			successColor.Printf("%s -> %s\n", filepath.Base(result.originalPath), filepath.Base(result.finalPath))
+		}
+		fmt.Println()
+		results = append(results, folderResults...)
+		confirmed += n
+	}
+
+	// 5. 整个目录树的结果记录为同一个批次, 可一次撤销
+	if batch != nil {
+		if err := batch.finish(results); err != nil {
+			warnColor.Fprintf(os.Stderr, "注意: 无法更新批次日志 %d: %v\n", batch.ID, err)
+		}
+	}
+	if len(results) > 0 {
+		fmt.Printf("一共完成 %d/%d 个文件\n", len(results), confirmed)
+		noticeColor.Printf("已记录批次 %d, 可使用 'tmrn undo --id %d' 撤销\n", batch.ID, batch.ID)
+	}
+
+	return nil
+}
+
+// planFolders 为每个目录生成计划
+// 全局编号时先对整个目录树的文件统一排序编号, 再按所在目录拆分
+func (r *Runner) planFolders(groups [][]fileInfo) ([]folderPlan, error) {
+	plans := make([]folderPlan, len(groups))
+	for i, files := range groups {
+		plans[i].dir = filepath.Dir(files[0].path)
+	}
+
+	if r.Numbering == numberingGlobal && !(r.ShuffleMode && r.ShuffleStyle == shufflePrefix) {
+		index := make(map[string]int, len(plans))
+		var all []fileInfo
+		for i, files := range groups {
+			index[plans[i].dir] = i
+			all = append(all, files...)
+		}
+
+		plan, err := r.planWithPolicy(all, nil, r.buildPlan)
+		if err != nil {
+			return nil, err
+		}
+		for _, op := range plan {
+			i := index[filepath.Dir(op.originalPath)]
+			plans[i].plan = append(plans[i].plan, op)
+		}
+	} else {
+		for i, files := range groups {
+			plan, err := r.planWithPolicy(files, nil, r.buildPlan)
+			if err != nil {
+				return nil, err
+			}
+			plans[i].plan = plan
+		}
+	}
+
+	for i := range plans {
+		plans[i].conflicts = findConflicts(plans[i].plan)
+	}
+	return plans, nil
+}
+
+// printFolderPlan 显示目录的序号、相对路径和计划
+func (r *Runner) printFolderPlan(i, total int, fp folderPlan, pageSize int) {
+	rel, err := filepath.Rel(r.DirPath, fp.dir)
+	if err != nil {
+		rel = fp.dir
+	}
+	fmt.Printf("[%d/%d] 目录: ", i+1, total)
+	noticeColor.Printf("%s\n", rel)
+	printPlan(fp.plan, fp.conflicts, pageSize)
+	fmt.Println()
+}
+
+// askFolderConfirmation 询问是否重命名当前目录, 返回 y (是), n (跳过), a (全部), q (退出)
+func askFolderConfirmation(renames int) string {
+	fmt.Printf("是否重命名该目录中的 %d 个文件? [y 是 / N 跳过 / a 全部 / q 退出]: ", renames)
+	switch answer := strings.ToLower(readLine()); answer {
+	case "y", "a", "q":
+		return answer
+	}
+	return "n"
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package cli
+
+// renameNoReplace 重命名文件, 目标已存在时返回 os.ErrExist 而不是覆盖它
+func renameNoReplace(oldpath, newpath string) error {
+	return linkRename(oldpath, newpath)
+}
@@ -3,10 +3,8 @@ package cli
 import (
 	"errors"
 	"fmt"
-	"math/rand/v2"
 	"os"
 	"path/filepath"
-	"slices"
 )
 
 // renameResult 单个文件重命名后的结果
@@ -15,73 +13,42 @@ type renameResult struct {
 	finalPath    string
 }
 
-// renameFilesByModTime 时间排序重命名
-func (r *Runner) renameFilesByModTime(files []fileInfo) ([]renameResult, error) {
-	if len(files) == 0 {
-		return nil, errors.New("没有找到匹配的文件")
-	}
-
-	// 依据文件修改时间进行排序
-	slices.SortFunc(files, func(a, b fileInfo) int {
-		if r.ReverseSort {
-			return b.modTime.Compare(a.modTime) // 降序
+// executePlan 分两阶段执行重命名计划, 名称不变的文件会被跳过
+// 先全部改为临时名称再改为最终名称, 避免目标名称与尚未处理的原名称互相覆盖
+func executePlan(plan []renameOp) ([]renameResult, error) {
+	var active []renameOp
+	for _, op := range plan {
+		if !op.unchanged() {
+			active = append(active, op)
 		}
-		return a.modTime.Compare(b.modTime) // 升序
-	})
-
-	// renameOp 单个文件的重命名计划
-	type renameOp struct {
-		originalPath string
-		tmpPath      string
-		finalPath    string
 	}
-
-	const tmpSuffix = ".tmrn-tmp"
-
-	// 步骤 1: 负责根据文件列表生成重命名计划
-	numFiles := len(files)
-	digits := len(fmt.Sprintf("%d", numFiles))
-	formatTemplate := fmt.Sprintf("%%0%dd", digits)
-	plan := make([]renameOp, numFiles)
-
-	for i, file := range files {
-		// 生成序号部分
-		numberPart := fmt.Sprintf(formatTemplate, i+1)
-
-		var finalName string
-		if r.NamePrefix != "" {
-			// 如果有前缀, 格式为: 前缀_序号.后缀
-			finalName = fmt.Sprintf("%s_%s%s", r.NamePrefix, numberPart, file.ext)
-		} else {
-			// 默认格式: 序号.后缀
-			finalName = numberPart + file.ext
-		}
-
-		plan[i] = renameOp{
-			originalPath: file.path,
-			tmpPath:      file.path + tmpSuffix,
-			finalPath:    filepath.Join(r.DirPath, finalName),
-		}
+	if len(active) == 0 {
+		return nil, errors.New("所有文件的名称都无需修改")
 	}
 
-	// 步骤 2: 执行第一阶段重命名 (原始文件 -> 临时文件)
+	// 步骤 1: 执行第一阶段重命名 (原始文件 -> 临时文件)
 	// 这一阶段是原子性的，如果中途失败，会尝试回滚所有已成功的操作。
-	for i, op := range plan {
-		if err := os.Rename(op.originalPath, op.tmpPath); err != nil {
+	for i, op := range active {
+		if err := renameNoReplace(op.originalPath, op.tmpPath); err != nil {
 			// 尝试回滚已成功的重命名操作
 			for j := range i {
 				// 尽力而为，忽略回滚错误
-				_ = os.Rename(plan[j].tmpPath, plan[j].originalPath)
+				_ = renameNoReplace(active[j].tmpPath, active[j].originalPath)
 			}
 			return nil, fmt.Errorf("操作已中断: 文件 '%s' 重命名失败: %w. 已尝试回滚", filepath.Base(op.originalPath), err)
 		}
 	}
 
-	// 步骤 3: 执行第二阶段重命名 (临时文件 -> 最终文件), 并收集结果
-	results := make([]renameResult, 0, len(plan))
-	for _, op := range plan {
-		if err := os.Rename(op.tmpPath, op.finalPath); err != nil {
-			warnColor.Fprintf(os.Stderr, "注意: 无法将 %s 重命名为 %s: %v\n", filepath.Base(op.tmpPath), filepath.Base(op.finalPath), err)
+	// 步骤 2: 执行第二阶段重命名 (临时文件 -> 最终文件), 并收集结果
+	// 目标名称可能在生成计划之后被其他程序占用, 此时不覆盖它, 保留临时文件留给 recover 处理
+	results := make([]renameResult, 0, len(active))
+	for _, op := range active {
+		if err := renameNoReplace(op.tmpPath, op.finalPath); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				warnColor.Fprintf(os.Stderr, "注意: %s 已被其他文件占用, 保留临时文件 %s (可使用 'tmrn recover' 恢复原名称)\n", filepath.Base(op.finalPath), filepath.Base(op.tmpPath))
+			} else {
+				warnColor.Fprintf(os.Stderr, "注意: 无法将 %s 重命名为 %s: %v (可使用 'tmrn recover' 恢复原名称)\n", filepath.Base(op.tmpPath), filepath.Base(op.finalPath), err)
+			}
 			continue // 继续处理下一个文件
 		}
 		// 将成功的结果添加到切片中
@@ -94,41 +61,18 @@ func (r *Runner) renameFilesByModTime(files []fileInfo) ([]renameResult, error)
 	return results, nil
 }
 
-// renameFilesWithRandomPrefix 随机前缀
-func (r *Runner) renameFilesWithRandomPrefix(files []fileInfo) ([]renameResult, error) {
-	if len(files) == 0 {
-		return nil, errors.New("没有找到匹配的文件")
+// linkRename 先创建硬链接再删除原名称, 硬链接在目标已存在时会失败, 因此不会覆盖任何文件
+// 文件系统不支持硬链接时只能先检查目标再重命名, 无法完全排除检查之后被占用的情况
+func linkRename(oldpath, newpath string) error {
+	err := os.Link(oldpath, newpath)
+	if err == nil {
+		return os.Remove(oldpath)
 	}
-
-	results := make([]renameResult, 0, len(files))
-
-	// 定义字符集 (大小写英文字母)
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-
-	for _, file := range files {
-		// 生成 4 位随机英文字符
-		randBytes := make([]byte, 4)
-		for i := range randBytes {
-			randBytes[i] = charset[rand.N(len(charset))]
-		}
-		prefix := string(randBytes)
-
-		// 构造新文件名: asdf_filename.ext
-		originalName := filepath.Base(file.path)
-		finalName := fmt.Sprintf("%s_%s", prefix, originalName)
-		finalPath := filepath.Join(r.DirPath, finalName)
-
-		// 执行重命名
-		if err := os.Rename(file.path, finalPath); err != nil {
-			warnColor.Fprintf(os.Stderr, "注意: 无法将 %s 重命名为 %s: %v\n", originalName, finalName, err)
-			continue
-		}
-
-		results = append(results, renameResult{
-			originalPath: file.path,
-			finalPath:    finalPath,
-		})
+	if errors.Is(err, os.ErrExist) {
+		return err
 	}
-
-	return results, nil
+	if _, statErr := os.Lstat(newpath); statErr == nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+	}
+	return os.Rename(oldpath, newpath)
 }
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameNoReplace(t *testing.T) {
+	renames := []struct {
+		name   string
+		rename func(oldpath, newpath string) error
+	}{
+		{"renameNoReplace", renameNoReplace},
+		{"linkRename", linkRename},
+	}
+
+	for _, tt := range renames {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "a.txt")
+			dst := filepath.Join(dir, "b.txt")
+			writeFile(t, src, "a")
+			writeFile(t, dst, "b")
+
+			// 目标已存在时不能覆盖, 两个文件都保持原样
+			err := tt.rename(src, dst)
+			if !errors.Is(err, os.ErrExist) {
+				t.Fatalf("rename onto an existing file: err = %v, want os.ErrExist", err)
+			}
+			assertContent(t, src, "a")
+			assertContent(t, dst, "b")
+
+			free := filepath.Join(dir, "c.txt")
+			if err := tt.rename(src, free); err != nil {
+				t.Fatalf("rename to a free name: %v", err)
+			}
+			assertContent(t, free, "a")
+			if _, err := os.Lstat(src); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("source still exists after rename: %v", err)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), data, want)
+	}
+}
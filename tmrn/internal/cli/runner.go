@@ -3,10 +3,14 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"tmrn/internal/media"
+
 	"github.com/fatih/color"
 )
 
@@ -14,20 +18,39 @@ var (
 	successColor = color.New(color.FgGreen)
 	warnColor    = color.New(color.FgCyan)
 	noticeColor  = color.New(color.FgYellow)
+	errorColor   = color.New(color.FgRed)
+	skipColor    = color.New(color.Faint)
 )
 
 // Runner 存储选项参数
 type Runner struct {
-	DirPath     string
-	FileExt     string
-	NamePrefix  string // 用于存储自定义文件名前缀
-	ReverseSort bool
-	ShuffleMode bool
+	DirPath      string
+	FileExts     []string // 只处理这些扩展名的文件
+	Types        []string // 按内容判断的文件类型: image, video, audio, doc
+	Includes     []string // 文件名需匹配其中一个通配符
+	Excludes     []string // 排除匹配这些通配符的文件
+	NamePrefix   string   // 用于存储自定义文件名前缀
+	ReverseSort  bool
+	ShuffleMode  bool
+	ShuffleStyle string // 随机模式的命名方式: prefix 添加随机前缀, number 随机编号
+	Seed         uint64 // 随机种子, 0 表示随机选择, 相同的种子和文件得到相同的结果
+	Legacy       bool   // 同时去掉旧版本添加的 "Abcd_" 随机前缀
+	DryRun       bool   // 只显示重命名计划, 不修改任何文件
+	SortBy       string // 排序方式, 见 sortKeys
+	Template     string // 文件名模板, 例如 "{date}_{n:03}"
+	Recursive    bool   // 递归处理所有子目录
+	Numbering    string // 递归时的编号方式: folder 每个目录单独编号, global 整个目录树统一编号
+	Hidden       bool   // 递归时包含隐藏目录
+	OnConflict   string // 目标名称被批次之外的文件占用时的处理方式, 见 conflictPolicies
+	Interactive  bool   // 在终端界面中调整顺序、排除文件和修改名称后再执行
+
+	template *nameTemplate // Validate 解析后的模板
+	kinds    []media.Kind  // Validate 解析后的文件类型
 }
 
 // NewRunner 构造函数 (也可以在这里设置参数默认值)
 func NewRunner() *Runner {
-	return &Runner{}
+	return &Runner{SortBy: sortByMtime, Numbering: numberingFolder, OnConflict: conflictAbort, ShuffleStyle: shufflePrefix}
 }
 
 // Validate 校验参数
@@ -64,25 +87,120 @@ func (r *Runner) Validate() error {
 		return fmt.Errorf("该路径不是一个目录 -> '%s'", dirPath)
 	}
 
-	// 确保文件格式以点号开头
-	if r.FileExt != "" {
-		r.FileExt = "." + strings.TrimPrefix(r.FileExt, ".")
+	// 校验筛选条件
+	if err := r.validateFilters(); err != nil {
+		return err
+	}
+
+	// 校验排序方式
+	r.SortBy = strings.ToLower(r.SortBy)
+	if !slices.Contains(sortKeys, r.SortBy) {
+		return fmt.Errorf("不支持的排序方式 -> '%s' (可选: %s)", r.SortBy, strings.Join(sortKeys, ", "))
+	}
+
+	// 校验编号方式
+	switch r.Numbering {
+	case numberingFolder:
+	case numberingGlobal:
+		if !r.Recursive {
+			return errors.New("--numbering global 需要与 --recursive 一起使用")
+		}
+	default:
+		return fmt.Errorf("不支持的编号方式 -> '%s' (可选: %s, %s)", r.Numbering, numberingFolder, numberingGlobal)
+	}
+
+	// 校验冲突处理方式
+	if !slices.Contains(conflictPolicies, r.OnConflict) {
+		return fmt.Errorf("不支持的冲突处理方式 -> '%s' (可选: %s)", r.OnConflict, strings.Join(conflictPolicies, ", "))
+	}
+
+	// 校验随机模式
+	switch {
+	case r.ShuffleStyle != shufflePrefix && r.ShuffleStyle != shuffleNumber:
+		return fmt.Errorf("不支持的随机方式 -> '%s' (可选: %s, %s)", r.ShuffleStyle, shufflePrefix, shuffleNumber)
+	case !r.ShuffleMode && (r.Seed != 0 || r.ShuffleStyle != shufflePrefix):
+		return errors.New("--seed 和 --shuffle-mode 需要与 --shuffle 一起使用")
+	case r.ShuffleMode && r.ShuffleStyle == shufflePrefix && (r.NamePrefix != "" || r.Template != ""):
+		return errors.New("添加随机前缀时不能使用 --name 或 --template, 可改用 --shuffle-mode number")
+	case r.Legacy && r.ShuffleMode && r.ShuffleStyle != shufflePrefix:
+		return errors.New("--legacy 只能在添加随机前缀时使用")
+	}
+	if r.ShuffleMode && r.Seed == 0 {
+		r.Seed = rand.Uint64()
+	}
+
+	// 校验交互模式
+	if r.Interactive {
+		switch {
+		case r.Recursive:
+			return errors.New("交互模式不支持 --recursive")
+		case r.ShuffleMode && r.ShuffleStyle == shufflePrefix:
+			return errors.New("交互模式不支持添加随机前缀, 可改用 --shuffle-mode number")
+		case r.OnConflict == conflictInclude:
+			return errors.New("交互模式中可以直接修改冲突的名称, 不支持 --on-conflict include")
+		case !isTerminal():
+			return errors.New("交互模式需要在终端中运行")
+		}
 	}
 
 	// 校验自定义前缀是否包含非法字符
 	if r.NamePrefix != "" {
-		const invalidChars = `/\:*?"'<>|`
-		if strings.ContainsAny(r.NamePrefix, invalidChars) {
-			return fmt.Errorf("自定义前缀包含非法字符 -> '%s' (禁止使用: %s)", r.NamePrefix, invalidChars)
+		if strings.ContainsAny(r.NamePrefix, invalidNameChars) {
+			return fmt.Errorf("自定义前缀包含非法字符 -> '%s' (禁止使用: %s)", r.NamePrefix, invalidNameChars)
 		}
 	}
 
+	// 解析文件名模板, 未指定时按前缀和序号命名
+	if r.Template != "" {
+		tmpl, err := parseTemplate(r.Template)
+		if err != nil {
+			return err
+		}
+		r.template = tmpl
+	} else {
+		r.template = sequenceTemplate(r.NamePrefix)
+	}
+
 	return nil
 }
 
 // Run 执行核心逻辑
 func (r *Runner) Run() error {
+	r.printHeader()
+
+	if r.Recursive {
+		return r.runRecursive()
+	}
+
+	// 1. 查找文件
+	if err := checkLeftovers([]string{r.DirPath}); err != nil {
+		return err
+	}
+	files, fallbacks, err := r.findFiles(r.DirPath)
+	if err != nil {
+		return fmt.Errorf("查找文件时出错: %w", err)
+	}
+	r.warnFallbacks(fallbacks)
+
+	if len(files) == 0 {
+		warnColor.Printf("没有找到匹配的文件\n")
+		return nil
+	}
+
+	// 2. 生成重命名计划, 交互模式中由用户调整后执行
+	if r.Interactive {
+		return r.runInteractive(files)
+	}
+	plan, err := r.planWithPolicy(files, nil, r.buildPlan)
+	if err != nil {
+		return fmt.Errorf("生成重命名计划时出错: %w", err)
+	}
+
+	return r.applyPlan(plan)
+}
 
+// printHeader 显示正在处理的目录
+func (r *Runner) printHeader() {
 	absPath, err := filepath.Abs(r.DirPath)
 	if err != nil {
 		// 如果无法获取绝对路径，则回退到使用原始路径
@@ -91,54 +209,78 @@ func (r *Runner) Run() error {
 	fmt.Printf("正在处理的目录: ")
 	noticeColor.Printf("%s\n\n", filepath.Base(absPath))
 
-	// 1. 查找文件
-	files, err := r.findFiles()
-	if err != nil {
-		return fmt.Errorf("查找文件时出错: %w", err)
+	if r.ShuffleMode {
+		skipColor.Printf("随机种子: %d (使用 --seed %d 可重现本次结果)\n\n", r.Seed, r.Seed)
 	}
+}
 
-	if len(files) == 0 {
-		warnColor.Printf("没有找到匹配的文件\n")
+// applyPlan 显示计划, 确认后执行并记录批次日志, 存在冲突时不修改任何文件
+func (r *Runner) applyPlan(plan []renameOp) error {
+	conflicts := findConflicts(plan)
+	renames, _ := countPlan(plan)
+
+	// 3. 预演模式只显示计划
+	if r.DryRun {
+		printPlan(plan, conflicts, 0)
+		warnColor.Printf("预演模式, 未修改任何文件\n")
 		return nil
 	}
 
-	// 2. 向用户确认
-	if !askForConfirmation("是否重命名 %d 个文件?", len(files)) {
+	// 4. 显示计划并向用户确认
+	printPlan(plan, conflicts, planPageSize())
+	if len(conflicts) > 0 {
+		return conflictError(len(conflicts))
+	}
+	if renames == 0 {
+		warnColor.Printf("所有文件的名称都无需修改\n")
+		return nil
+	}
+	if !askForConfirmation("是否重命名 %d 个文件?", renames) {
 		warnColor.Printf("操作已取消\n")
 		return nil
 	}
 
-	// 3. 重命名文件
-	var results []renameResult
+	return r.executeBatch(plan, renames)
+}
 
-	if r.ShuffleMode {
-		// 随机模式, 添加随机前缀
-		results, err = r.renameFilesWithRandomPrefix(files)
-	} else {
-		// 默认模式, 时间排序逻辑
-		results, err = r.renameFilesByModTime(files)
+// executeBatch 执行已确认的计划, 显示结果并记录批次日志
+// 日志在重命名之前写入, 进程中途退出时也能据此撤销已完成的部分
+func (r *Runner) executeBatch(plan []renameOp, renames int) error {
+	// 5. 记录批次日志, 无法记录时不修改任何文件
+	j, err := createJournal(r.DirPath, plan)
+	if err != nil {
+		return fmt.Errorf("无法记录批次日志, 未修改任何文件: %w", err)
 	}
 
+	// 6. 重命名文件
+	results, err := executePlan(plan)
+	if finishErr := j.finish(results); finishErr != nil {
+		warnColor.Fprintf(os.Stderr, "注意: 无法更新批次日志 %d: %v\n", j.ID, finishErr)
+	}
 	if err != nil {
 		return fmt.Errorf("重命名文件时出错: %w", err)
 	}
 
-	// 4. 打印成功的结果
+	// 7. 打印成功的结果
 	for _, result := range results {
 		successColor.Printf("%s -> %s\n", filepath.Base(result.originalPath), filepath.Base(result.finalPath))
 	}
 
 	if len(results) > 0 {
-		fmt.Printf("\n一共完成 %d/%d 个文件\n", len(results), len(files))
+		fmt.Printf("\n一共完成 %d/%d 个文件\n", len(results), renames)
+		noticeColor.Printf("已记录批次 %d, 可使用 'tmrn undo --id %d' 撤销\n", j.ID, j.ID)
 	}
 
 	return nil
 }
 
+// conflictError 存在冲突时返回的错误
+func conflictError(n int) error {
+	return fmt.Errorf("存在 %d 个冲突, 未修改任何文件 (与已有文件的冲突可使用 --on-conflict free 或 include 处理)", n)
+}
+
 // askForConfirmation 辅助函数, 询问用户是否继续
 func askForConfirmation(format string, a ...any) bool {
 	fmt.Printf(format+" [y/N]: ", a...)
-	var response string
-	fmt.Scanln(&response)
-	return strings.ToLower(strings.TrimSpace(response)) == "y"
+	return strings.ToLower(readLine()) == "y"
 }
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// 随机模式的命名方式
+const (
+	shufflePrefix = "prefix" // 添加 4 位随机英文前缀和 "~", 保留原名称
+	shuffleNumber = "number" // 随机排列后按模板重新编号
+)
+
+// prefixCharset 随机前缀的字符集 (大小写英文字母)
+const prefixCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// shuffleSep 随机前缀与原名称之间的分隔符
+// 普通文件名中很少在这个位置出现 "~", 据此可以区分 tmrn 添加的前缀和 "user_guide" 这样的原名称
+const shuffleSep = "~"
+
+// shufflePrefixRe 随机前缀的格式: 4 位英文字母加分隔符
+var shufflePrefixRe = regexp.MustCompile(`^[A-Za-z]{4}` + shuffleSep)
+
+// legacyPrefixRe 旧版本使用下划线作为分隔符, 与 "user_guide" 这样的原名称无法区分, 只在指定 --legacy 时识别
+var legacyPrefixRe = regexp.MustCompile(`^[A-Za-z]{4}_`)
+
+// newRand 根据 Validate 确定的种子创建随机数生成器
+func (r *Runner) newRand() *rand.Rand {
+	return rand.New(rand.NewPCG(r.Seed, r.Seed))
+}
+
+// planRandomPrefix 为每个文件添加 4 位随机英文前缀, 已有的随机前缀会被替换而不是叠加
+func (r *Runner) planRandomPrefix(files []fileInfo, rng *rand.Rand) []renameOp {
+	stripped := stripShufflePrefixes(files, r.Legacy)
+
+	// 按去掉前缀后的名称排序, 同一个种子对同一组文件总是生成相同的前缀
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Or(strings.Compare(stripped[a], stripped[b]), strings.Compare(files[a].path, files[b].path))
+	})
+
+	used := make(map[string]bool, len(files))
+	plan := make([]renameOp, len(files))
+	for _, i := range order {
+		// 前缀在批次内不重复, 保证排序结果是一个真正的随机排列
+		prefix := randomPrefix(rng)
+		for used[strings.ToLower(prefix)] {
+			prefix = randomPrefix(rng)
+		}
+		used[strings.ToLower(prefix)] = true
+
+		// 构造新文件名: asdf~filename.ext
+		finalName := prefix + shuffleSep + stripped[i]
+		plan[i] = newRenameOp(files[i].path, filepath.Join(filepath.Dir(files[i].path), finalName))
+	}
+
+	return plan
+}
+
+// planRandomNumbers 将文件随机排列后按模板编号, 不添加前缀
+func (r *Runner) planRandomNumbers(files []fileInfo, rng *rand.Rand) ([]renameOp, error) {
+	shuffleFiles(files, rng)
+	return r.planNumbered(files)
+}
+
+// shuffleFiles 随机排列文件, 先按路径排序, 保证同一个种子的结果可以重现
+func shuffleFiles(files []fileInfo, rng *rand.Rand) {
+	slices.SortFunc(files, func(a, b fileInfo) int {
+		return strings.Compare(a.path, b.path)
+	})
+	rng.Shuffle(len(files), func(i, j int) {
+		files[i], files[j] = files[j], files[i]
+	})
+}
+
+// randomPrefix 生成 4 位随机英文字符
+func randomPrefix(rng *rand.Rand) string {
+	randBytes := make([]byte, 4)
+	for j := range randBytes {
+		randBytes[j] = prefixCharset[rng.IntN(len(prefixCharset))]
+	}
+	return string(randBytes)
+}
+
+// stripShufflePrefixes 返回每个文件去掉随机前缀后的名称, 每次只去掉一层
+// 默认只识别带有 "~" 分隔符的前缀, "user_guide.pdf" "blog_post.md" 这样的原名称不会被误认为带有前缀;
+// legacy 为 true 时还会去掉旧版本添加的下划线前缀, 包括重新随机时叠加在它前面的新前缀, 如 "Abcd~efgh_x.jpg"
+func stripShufflePrefixes(files []fileInfo, legacy bool) []string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		name := filepath.Base(file.path)
+		names[i] = name
+
+		stripped := false
+		if prefix := shufflePrefixRe.FindString(name); prefix != "" {
+			if rest := name[len(prefix):]; rest != "" && !strings.HasPrefix(rest, ".") {
+				names[i], stripped = rest, true
+			}
+		}
+		if !legacy || (stripped && shufflePrefixRe.MatchString(names[i])) {
+			continue
+		}
+		if prefix := legacyPrefixRe.FindString(names[i]); prefix != "" {
+			if rest := names[i][len(prefix):]; rest != "" && !strings.HasPrefix(rest, ".") {
+				names[i] = rest
+			}
+		}
+	}
+	return names
+}
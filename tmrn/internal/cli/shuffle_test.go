@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStripShufflePrefixes(t *testing.T) {
+	tests := []struct {
+		name   string
+		legacy bool
+		want   string
+	}{
+		// 普通文件名中的 "word_" 不是随机前缀
+		{"user_guide.pdf", false, "user_guide.pdf"},
+		{"blog_post.md", false, "blog_post.md"},
+		{"trip_001.jpg", false, "trip_001.jpg"},
+		{"Abcd_notes.txt", false, "Abcd_notes.txt"},
+
+		{"Abcd~notes.txt", false, "notes.txt"},
+		{"xYzW~user_guide.pdf", false, "user_guide.pdf"},
+		{"Abcd~Efgh~a.txt", false, "Efgh~a.txt"},
+		{"Abcd~", false, "Abcd~"},
+		{"Abcd~.bashrc", false, "Abcd~.bashrc"},
+		{"Ab1d~notes.txt", false, "Ab1d~notes.txt"},
+		{"notes~Abcd.txt", false, "notes~Abcd.txt"},
+		{"Abcd~efgh_x.jpg", false, "efgh_x.jpg"},
+
+		// --legacy 时同时去掉旧版本的下划线前缀, 包括叠加在新前缀之后的
+		{"Qwer_holiday.jpg", true, "holiday.jpg"},
+		{"Abcd~efgh_x.jpg", true, "x.jpg"},
+		{"Abcd~notes.txt", true, "notes.txt"},
+		{"Abcd~Efgh~a.txt", true, "Efgh~a.txt"},
+		{"Abcd_.bashrc", true, "Abcd_.bashrc"},
+		{"Abcd_", true, "Abcd_"},
+		// 无法区分旧前缀和普通的四个字母加下划线, 所以 --legacy 需要显式开启
+		{"trip_001.jpg", true, "001.jpg"},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		files := []fileInfo{{path: filepath.Join(dir, tt.name)}}
+		if got := stripShufflePrefixes(files, tt.legacy)[0]; got != tt.want {
+			t.Errorf("strip(%q, legacy=%v) = %q, want %q", tt.name, tt.legacy, got, tt.want)
+		}
+	}
+}
+
+func TestPlanRandomPrefix(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	names := []string{"user_guide.pdf", "blog_post.md", "Abcd~photo.jpg", "b.txt"}
+	files := make([]fileInfo, len(names))
+	for i, name := range names {
+		files[i] = fileInfo{path: filepath.Join(dir, name)}
+	}
+
+	r := NewRunner()
+	r.Seed = 42
+	plan := r.planRandomPrefix(files, r.newRand())
+	again := r.planRandomPrefix(files, r.newRand())
+
+	want := []string{"user_guide.pdf", "blog_post.md", "photo.jpg", "b.txt"}
+	var prefixes []string
+	for i, op := range plan {
+		final := filepath.Base(op.finalPath)
+		prefix := shufflePrefixRe.FindString(final)
+		if prefix == "" {
+			t.Fatalf("%s -> %s: no shuffle prefix", names[i], final)
+		}
+		// 已有的随机前缀被替换, 而不是叠加
+		if rest := strings.TrimPrefix(final, prefix); rest != want[i] {
+			t.Errorf("%s -> %s: name after prefix = %q, want %q", names[i], final, rest, want[i])
+		}
+		if final != filepath.Base(again[i].finalPath) {
+			t.Errorf("same seed gave %s and %s", final, filepath.Base(again[i].finalPath))
+		}
+		prefixes = append(prefixes, strings.ToLower(prefix))
+	}
+
+	slices.Sort(prefixes)
+	if len(slices.Compact(prefixes)) != len(names) {
+		t.Errorf("prefixes are not unique: %v", prefixes)
+	}
+
+	// --legacy 时旧版本的下划线前缀也被替换
+	r.Legacy = true
+	files = []fileInfo{{path: filepath.Join(dir, "Qwer_holiday.jpg")}, {path: filepath.Join(dir, "Abcd~efgh_x.jpg")}}
+	for i, op := range r.planRandomPrefix(files, r.newRand()) {
+		final := filepath.Base(op.finalPath)
+		want := []string{"holiday.jpg", "x.jpg"}[i]
+		if rest := shufflePrefixRe.ReplaceAllString(final, ""); rest != want {
+			t.Errorf("legacy %s -> %s, want a prefix followed by %q", filepath.Base(files[i].path), final, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"tmrn/internal/media"
+)
+
+// 支持的排序方式
+const (
+	sortByExif  = "exif"  // 照片 EXIF 拍摄时间或视频创建时间
+	sortByMtime = "mtime" // 修改时间
+	sortByCtime = "ctime" // 状态改变时间
+	sortByBtime = "btime" // 创建时间
+	sortByName  = "name"  // 文件名
+	sortBySize  = "size"  // 文件大小
+)
+
+// sortKeys 所有支持的排序方式
+var sortKeys = []string{sortByExif, sortByMtime, sortByCtime, sortByBtime, sortByName, sortBySize}
+
+// sortTimeLabels 按时间排序时对应的时间名称, 用于提示回退到修改时间的文件
+var sortTimeLabels = map[string]string{
+	sortByExif:  "拍摄时间",
+	sortByCtime: "状态改变时间",
+	sortByBtime: "创建时间",
+}
+
+// fileTime 返回排序使用的时间, 无法获取时回退到修改时间并返回 false
+func (r *Runner) fileTime(path string, info os.FileInfo) (time.Time, bool) {
+	var (
+		t  time.Time
+		ok bool
+	)
+	switch r.SortBy {
+	case sortByExif:
+		meta, err := media.Read(path)
+		if err != nil && !errors.Is(err, media.ErrNoMetadata) {
+			warnColor.Fprintf(os.Stderr, "注意: 读取元数据失败 %s: %v\n", filepath.Base(path), err)
+		}
+		t, ok = meta.Time, !meta.Time.IsZero()
+	case sortByCtime:
+		t, ok = changeTime(path, info)
+	case sortByBtime:
+		t, ok = birthTime(path, info)
+	default:
+		return info.ModTime(), true
+	}
+
+	if !ok {
+		return info.ModTime(), false
+	}
+	return t, true
+}
+
+// sortFiles 按排序方式对文件排序, 相同时按文件名排序以保证结果稳定
+func (r *Runner) sortFiles(files []fileInfo) {
+	slices.SortFunc(files, func(a, b fileInfo) int {
+		var c int
+		switch r.SortBy {
+		case sortByName:
+			c = strings.Compare(filepath.Base(a.path), filepath.Base(b.path))
+		case sortBySize:
+			c = cmp.Compare(a.size, b.size)
+		default:
+			c = a.sortTime.Compare(b.sortTime)
+		}
+		if c == 0 {
+			c = strings.Compare(filepath.Base(a.path), filepath.Base(b.path))
+		}
+
+		if r.ReverseSort {
+			return -c // 降序
+		}
+		return c // 升序
+	})
+}
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 大小写转换方式
+const (
+	caseNone  = ""
+	caseLower = "lower"
+	caseUpper = "upper"
+	caseTitle = "title"
+)
+
+// SubRunner 存储 sub 子命令的选项参数, 目录相关的选项与默认命令共用
+type SubRunner struct {
+	Runner
+	Pattern     string // 正则表达式, 为空时只做大小写转换和音译
+	Replacement string // 替换内容, 支持 $1 / \1 引用分组, 以及 \U \L \E \u \l 大小写转换
+	IgnoreCase  bool
+	Literal     bool   // 按普通文本匹配和替换
+	WithExt     bool   // 同时处理扩展名, 默认只处理主文件名
+	Case        string // 替换后整体转换大小写: lower, upper, title
+	Translit    bool   // 将带变音符号的字母和全角字符转换为 ASCII
+
+	re   *regexp.Regexp
+	repl []replPart
+}
+
+// replPart 替换内容中的一段, 展开分组引用后按 mode 转换大小写, first 只作用于第一个字符
+type replPart struct {
+	text  string
+	mode  string
+	first string
+}
+
+// NewSubRunner 构造函数, 文件按名称排序显示
+func NewSubRunner() *SubRunner {
+	r := &SubRunner{Runner: *NewRunner()}
+	r.SortBy = sortByName
+	return r
+}
+
+// Validate 校验参数并编译正则表达式
+func (r *SubRunner) Validate() error {
+	if err := r.Runner.Validate(); err != nil {
+		return err
+	}
+
+	switch r.Case {
+	case caseNone, caseLower, caseUpper, caseTitle:
+	default:
+		return fmt.Errorf("不支持的大小写转换 -> '%s' (可选: %s, %s, %s)", r.Case, caseLower, caseUpper, caseTitle)
+	}
+
+	if r.Pattern == "" {
+		if r.Case == caseNone && !r.Translit {
+			return errors.New("匹配模式为空时需要指定 --case 或 --translit")
+		}
+		return nil
+	}
+
+	pattern := r.Pattern
+	if r.Literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if r.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("无效的正则表达式 -> '%s': %w", r.Pattern, err)
+	}
+	r.re = re
+
+	if r.Literal {
+		r.repl = []replPart{{text: strings.ReplaceAll(r.Replacement, "$", "$$")}}
+	} else {
+		r.repl = parseReplacement(r.Replacement)
+	}
+	return nil
+}
+
+// Run 对匹配的文件名执行替换, 与默认命令共用两阶段重命名和批次日志
+func (r *SubRunner) Run() error {
+	r.printHeader()
+
+	// 1. 查找文件
+	dirs, err := r.findDirs()
+	if err != nil {
+		return err
+	}
+	if err := checkLeftovers(dirs); err != nil {
+		return err
+	}
+
+	// 2. 生成重命名计划, 只包含名称发生变化的文件
+	var plan []renameOp
+	matched := 0
+	for _, dir := range dirs {
+		files, _, err := r.findFiles(dir)
+		if err != nil {
+			warnColor.Printf("注意: %v\n", err)
+			continue
+		}
+		matched += len(files)
+
+		dirPlan, err := r.planWithPolicy(files, nil, r.planSub)
+		if err != nil {
+			return err
+		}
+		plan = append(plan, dirPlan...)
+	}
+
+	if matched == 0 {
+		warnColor.Printf("没有找到匹配的文件\n")
+		return nil
+	}
+	if len(plan) == 0 {
+		warnColor.Printf("所有文件的名称都无需修改\n")
+		return nil
+	}
+
+	return r.applyPlan(plan)
+}
+
+// planSub 按名称排序后为名称发生变化的文件生成计划
+func (r *SubRunner) planSub(files []fileInfo) ([]renameOp, error) {
+	r.sortFiles(files)
+
+	var plan []renameOp
+	for _, file := range files {
+		oldName := filepath.Base(file.path)
+		newName := r.rewrite(oldName)
+		if newName == oldName {
+			continue
+		}
+		if err := validateSubName(newName); err != nil {
+			return nil, fmt.Errorf("%s 的新名称无效: %w", oldName, err)
+		}
+		plan = append(plan, newRenameOp(file.path, filepath.Join(filepath.Dir(file.path), newName)))
+	}
+	return plan, nil
+}
+
+// validateSubName 检查替换后的名称
+// 只拒绝无法作为单个文件名的结果, 原名称中本来就有的 ' 和 : 等字符不受 invalidNameChars 限制
+func validateSubName(name string) error {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("名称为空 -> '%s'", name)
+	case strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, 0):
+		return fmt.Errorf("'%s' 包含路径分隔符或空字符", name)
+	case strings.HasSuffix(name, tmpSuffix):
+		return fmt.Errorf("'%s' 不能以 %s 结尾", name, tmpSuffix)
+	}
+	return nil
+}
+
+// rewrite 依次执行替换、音译和大小写转换, 未指定 --with-ext 时保留扩展名
+func (r *SubRunner) rewrite(name string) string {
+	stem, ext := name, ""
+	if !r.WithExt {
+		ext = filepath.Ext(name)
+		stem = strings.TrimSuffix(name, ext)
+	}
+
+	if r.re != nil {
+		stem = r.substitute(stem)
+	}
+	if r.Translit {
+		stem = transliterate(stem)
+	}
+	switch r.Case {
+	case caseLower:
+		stem = strings.ToLower(stem)
+	case caseUpper:
+		stem = strings.ToUpper(stem)
+	case caseTitle:
+		stem = titleCase(stem)
+	}
+
+	return stem + ext
+}
+
+// substitute 替换所有匹配, 逐段展开分组引用并转换大小写
+func (r *SubRunner) substitute(s string) string {
+	matches := r.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		pending := caseNone
+		for _, part := range r.repl {
+			text := string(r.re.ExpandString(nil, part.text, s, m))
+			text = convertCase(text, part.mode)
+			if part.first != caseNone {
+				pending = part.first
+			}
+			if pending != caseNone && text != "" {
+				_, size := utf8.DecodeRuneInString(text)
+				text = convertCase(text[:size], pending) + text[size:]
+				pending = caseNone
+			}
+			b.WriteString(text)
+		}
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// parseReplacement 将替换内容按 \U \L \E \u \l 拆分成多段, 并把 $1 和 \1 形式的引用转换为 ${1}
+func parseReplacement(s string) []replPart {
+	var parts []replPart
+	var text strings.Builder
+	mode, first := caseNone, caseNone
+
+	flush := func() {
+		if text.Len() > 0 || first != caseNone {
+			parts = append(parts, replPart{text: text.String(), mode: mode, first: first})
+			text.Reset()
+			first = caseNone
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		// $1_x 在 regexp 中会被当作名为 "1_x" 的分组, 这里把数字引用固定为 ${1}
+		if s[i] == '$' && i+1 < len(s) {
+			j := i + 1
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			switch {
+			case j > i+1:
+				fmt.Fprintf(&text, "${%s}", s[i+1:j])
+				i = j - 1
+			case s[i+1] == '$':
+				text.WriteString("$$")
+				i++
+			default:
+				text.WriteByte('$')
+			}
+			continue
+		}
+		if s[i] != '\\' || i+1 == len(s) {
+			text.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch c := s[i]; {
+		case c == 'U':
+			flush()
+			mode = caseUpper
+		case c == 'L':
+			flush()
+			mode = caseLower
+		case c == 'E':
+			flush()
+			mode = caseNone
+		case c == 'u':
+			flush()
+			first = caseUpper
+		case c == 'l':
+			flush()
+			first = caseLower
+		case c >= '0' && c <= '9':
+			fmt.Fprintf(&text, "${%c}", c)
+		default:
+			// \\ 等其他转义保留后一个字符
+			text.WriteByte(c)
+		}
+	}
+	flush()
+	return parts
+}
+
+// convertCase 按 mode 转换整段文本的大小写
+func convertCase(s, mode string) string {
+	switch mode {
+	case caseUpper:
+		return strings.ToUpper(s)
+	case caseLower:
+		return strings.ToLower(s)
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubRewrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		replacement string
+		literal     bool
+		ignoreCase  bool
+		withExt     bool
+		input       string
+		want        string
+	}{
+		{"group followed by underscore", `(\w+)-(\d+)`, "$1_x$2", false, false, false, "photo-12.jpg", "photo_x12.jpg"},
+		{"braced group", `(\d+)`, "${1}0", false, false, false, "a7.txt", "a70.txt"},
+		{"backslash group", `(\w+) (\w+)`, `\2 \1`, false, false, false, "hello world.txt", "world hello.txt"},
+		{"upper until end", `^(\w+)_(\w+)$`, `\U$1\E_$2`, false, false, false, "foo_bar.txt", "FOO_bar.txt"},
+		{"upper to end of replacement", `^(\w+)$`, `\U$1`, false, false, false, "foo.txt", "FOO.txt"},
+		{"lower section", `^(\w+)$`, `\L$1`, false, false, false, "FooBar.txt", "foobar.txt"},
+		{"upper first letter", `^(\w+)$`, `\u$1`, false, false, false, "report.txt", "Report.txt"},
+		{"lower first letter", `^(\w+)$`, `\l$1`, false, false, false, "README.md", "rEADME.md"},
+		{"first letter inside lower section", `^(\w+)$`, `\u\L$1`, false, false, false, "HELLO.txt", "Hello.txt"},
+		{"escaped dollar", `^`, `$$`, false, false, false, "5.txt", "$5.txt"},
+		{"trailing dollar", `x`, `$`, false, false, false, "axb.txt", "a$b.txt"},
+		{"literal mode keeps dollar", `a.b`, `$1`, true, false, false, "a.b-axb.txt", "$1-axb.txt"},
+		{"ignore case", `img`, `photo`, false, true, false, "IMG_01.jpg", "photo_01.jpg"},
+		{"extension untouched by default", `jpg`, `png`, false, false, false, "jpg.jpg", "png.jpg"},
+		{"with extension", `\.jpeg$`, `.jpg`, false, false, true, "a.jpeg", "a.jpg"},
+		{"no match", `zzz`, `y`, false, false, false, "a.txt", "a.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSubRunner()
+			r.DirPath = "."
+			r.Pattern = tt.pattern
+			r.Replacement = tt.replacement
+			r.Literal = tt.literal
+			r.IgnoreCase = tt.ignoreCase
+			r.WithExt = tt.withExt
+			if err := r.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if got := r.rewrite(tt.input); got != tt.want {
+				t.Errorf("rewrite(%q) with %q -> %q = %q, want %q", tt.input, tt.pattern, tt.replacement, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanSubValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		replacement string
+		input       string
+		want        string // 期望的新名称, 为空时期望报错
+		wantErr     string
+	}{
+		{"keeps apostrophe already in the name", "", "", "It's.jpg", "it's.jpg", ""},
+		{"keeps colon already in the name", `\s`, "_", "10:30 am.txt", "10:30_am.txt", ""},
+		{"introduced slash", `-`, "/", "a-b.txt", "", "路径分隔符"},
+		{"introduced temporary suffix", `$`, ".tmrn-tmp", "a", "", "不能以"},
+		{"empty name", `.+`, "", "abc", "", "名称为空"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSubRunner()
+			r.DirPath = "."
+			r.Pattern = tt.pattern
+			r.Replacement = tt.replacement
+			r.WithExt = true
+			if tt.pattern == "" {
+				r.Case = caseLower
+			}
+			if err := r.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			plan, err := r.planSub([]fileInfo{{path: filepath.Join(t.TempDir(), tt.input)}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("planSub(%q) error = %v, want it to contain %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planSub(%q): %v", tt.input, err)
+			}
+			if len(plan) != 1 || filepath.Base(plan[0].finalPath) != tt.want {
+				t.Errorf("planSub(%q) = %v, want %q", tt.input, plan, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tmrn/internal/media"
+)
+
+// invalidNameChars 文件名中禁止使用的字符
+const invalidNameChars = `/\:*?"'<>|`
+
+// 模板中各个变量的默认参数
+const (
+	defaultDateLayout = "2006-01-02"
+	defaultTimeLayout = "150405"
+	defaultHashLen    = 8
+	unknownCamera     = "unknown"
+)
+
+// templateSegment 模板中的一段, token 为空时是普通文本
+type templateSegment struct {
+	text  string
+	token string
+	arg   string
+}
+
+// nameTemplate 解析后的文件名模板, 例如 "{date}_{n:03}"
+type nameTemplate struct {
+	segments []templateSegment
+	hasExt   bool              // 模板中包含 {ext}, 否则自动追加原扩展名
+	hashes   map[string]string // 已计算的文件哈希, 交互模式中每次调整都会重新生成名称
+}
+
+// parseTemplate 解析并校验文件名模板
+func parseTemplate(s string) (*nameTemplate, error) {
+	t := &nameTemplate{}
+	for rest := s; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if close := strings.IndexByte(rest, '}'); close >= 0 && (open < 0 || close < open) {
+			return nil, fmt.Errorf("模板中有多余的 '}' -> '%s'", s)
+		}
+		if open < 0 {
+			t.segments = append(t.segments, templateSegment{text: rest})
+			break
+		}
+		if open > 0 {
+			t.segments = append(t.segments, templateSegment{text: rest[:open]})
+		}
+
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("模板中的 '{' 没有闭合 -> '%s'", s)
+		}
+		token, arg, _ := strings.Cut(rest[open+1:open+end], ":")
+		seg := templateSegment{token: token, arg: arg}
+		if err := seg.validate(); err != nil {
+			return nil, err
+		}
+		if token == "ext" {
+			t.hasExt = true
+		}
+		t.segments = append(t.segments, seg)
+		rest = rest[open+end+1:]
+	}
+
+	for _, seg := range t.segments {
+		if seg.token == "" && strings.ContainsAny(seg.text, invalidNameChars) {
+			return nil, fmt.Errorf("模板包含非法字符 -> '%s' (禁止使用: %s)", s, invalidNameChars)
+		}
+	}
+	return t, nil
+}
+
+// validate 检查变量名和参数
+func (seg templateSegment) validate() error {
+	switch seg.token {
+	case "n":
+		if seg.arg != "" {
+			if width, err := strconv.Atoi(seg.arg); err != nil || width <= 0 || width > 20 {
+				return fmt.Errorf("序号宽度无效 -> '{n:%s}' (例如 {n:03})", seg.arg)
+			}
+		}
+	case "date", "time":
+		// 格式中的冒号等字符会被拒绝, 这里用当前时间试着格式化一次
+		if seg.arg != "" && strings.ContainsAny(time.Now().Format(seg.arg), invalidNameChars) {
+			return fmt.Errorf("时间格式会产生非法字符 -> '{%s:%s}' (禁止使用: %s)", seg.token, seg.arg, invalidNameChars)
+		}
+	case "hash":
+		if seg.arg != "" {
+			if n, err := strconv.Atoi(seg.arg); err != nil || n <= 0 || n > sha256.Size*2 {
+				return fmt.Errorf("哈希长度无效 -> '{hash:%s}' (1-%d)", seg.arg, sha256.Size*2)
+			}
+		}
+	case "name", "ext", "parent", "exif.camera":
+		if seg.arg != "" {
+			return fmt.Errorf("变量 {%s} 不接受参数", seg.token)
+		}
+	default:
+		return fmt.Errorf("未知的模板变量 -> '{%s}'", seg.token)
+	}
+	return nil
+}
+
+// sequenceTemplate 未指定模板时使用的默认命名: "序号.后缀" 或 "前缀_序号.后缀"
+func sequenceTemplate(prefix string) *nameTemplate {
+	t := &nameTemplate{}
+	if prefix != "" {
+		t.segments = append(t.segments, templateSegment{text: prefix + "_"})
+	}
+	t.segments = append(t.segments, templateSegment{token: "n"})
+	return t
+}
+
+// uses 模板中是否包含指定变量
+func (t *nameTemplate) uses(token string) bool {
+	for _, seg := range t.segments {
+		if seg.token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// render 为排序后的第 n 个文件生成新文件名, total 用于计算 {n} 的默认宽度
+func (t *nameTemplate) render(n, total int, file fileInfo) (string, error) {
+	var b strings.Builder
+	base := filepath.Base(file.path)
+
+	var info media.Info
+	if t.uses("exif.camera") {
+		// 读取失败时只影响 {exif.camera}, 显示为 unknown
+		info, _ = media.Read(file.path)
+	}
+
+	for _, seg := range t.segments {
+		switch seg.token {
+		case "":
+			b.WriteString(seg.text)
+		case "n":
+			width := len(strconv.Itoa(total))
+			if seg.arg != "" {
+				width, _ = strconv.Atoi(seg.arg)
+			}
+			fmt.Fprintf(&b, "%0*d", width, n)
+		case "date":
+			b.WriteString(file.sortTime.Format(cmp.Or(seg.arg, defaultDateLayout)))
+		case "time":
+			b.WriteString(file.sortTime.Format(cmp.Or(seg.arg, defaultTimeLayout)))
+		case "name":
+			b.WriteString(sanitizeName(strings.TrimSuffix(base, file.ext)))
+		case "ext":
+			b.WriteString(sanitizeName(strings.TrimPrefix(file.ext, ".")))
+		case "parent":
+			parent, err := filepath.Abs(filepath.Dir(file.path))
+			if err != nil {
+				parent = filepath.Dir(file.path)
+			}
+			b.WriteString(sanitizeName(filepath.Base(parent)))
+		case "exif.camera":
+			b.WriteString(sanitizeName(cmp.Or(info.Camera(), unknownCamera)))
+		case "hash":
+			length := defaultHashLen
+			if seg.arg != "" {
+				length, _ = strconv.Atoi(seg.arg)
+			}
+			sum, err := t.fileHash(file.path)
+			if err != nil {
+				return "", fmt.Errorf("计算哈希失败 %s: %w", base, err)
+			}
+			b.WriteString(sum[:length])
+		}
+	}
+
+	if !t.hasExt {
+		b.WriteString(sanitizeName(file.ext))
+	}
+
+	name := b.String()
+	if err := validateName(name); err != nil {
+		return "", fmt.Errorf("%s 的新名称无效: %w", base, err)
+	}
+	return name, nil
+}
+
+// fileHash 返回文件的哈希, 同一个文件只计算一次
+func (t *nameTemplate) fileHash(path string) (string, error) {
+	if sum, ok := t.hashes[path]; ok {
+		return sum, nil
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	if t.hashes == nil {
+		t.hashes = make(map[string]string)
+	}
+	t.hashes[path] = sum
+	return sum, nil
+}
+
+// validateName 检查生成的文件名, 规则与自定义前缀相同
+func validateName(name string) error {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("名称为空 -> '%s'", name)
+	case strings.ContainsAny(name, invalidNameChars):
+		return fmt.Errorf("'%s' 包含非法字符 (禁止使用: %s)", name, invalidNameChars)
+	case strings.HasSuffix(name, tmpSuffix):
+		return fmt.Errorf("'%s' 不能以 %s 结尾", name, tmpSuffix)
+	}
+	return nil
+}
+
+// sanitizeName 将原文件名或元数据中的非法字符替换为下划线, 避免个别文件的名称让整批重命名失败
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalidNameChars, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// hashFile 返回文件内容 SHA-256 的十六进制字符串
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{"unknown token", "{foo}", "未知的模板变量"},
+		{"unclosed brace", "{n", "没有闭合"},
+		{"stray closing brace", "a}b{n}", "多余的 '}'"},
+		{"zero width", "{n:0}", "序号宽度无效"},
+		{"non-numeric width", "{n:x}", "序号宽度无效"},
+		{"width too large", "{n:21}", "序号宽度无效"},
+		{"zero hash length", "{hash:0}", "哈希长度无效"},
+		{"hash too long", "{hash:65}", "哈希长度无效"},
+		{"argument not accepted", "{name:1}", "不接受参数"},
+		{"invalid character", "a/b_{n}", "非法字符"},
+		{"date layout with colon", "{time:15:04}", "非法字符"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTemplate(tt.template)
+			if err == nil {
+				t.Fatalf("parseTemplate(%q) succeeded, want error containing %q", tt.template, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseTemplate(%q) error = %q, want it to contain %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "trip")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "IMG_0042.jpg")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := fileInfo{
+		path:     path,
+		sortTime: time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC),
+		ext:      ".jpg",
+	}
+
+	// sha256("hello") = 2cf24dba5fb0a30e...
+	tests := []struct {
+		template string
+		n, total int
+		want     string
+	}{
+		{"{n:03}", 7, 12, "007.jpg"},
+		{"{n}", 7, 120, "007.jpg"},
+		{"{n}", 7, 9, "7.jpg"},
+		{"{n:1}", 123, 200, "123.jpg"},
+		{"{hash:8}", 1, 1, "2cf24dba.jpg"},
+		{"{hash}", 1, 1, "2cf24dba.jpg"},
+		{"{hash:12}_{n:02}", 3, 5, "2cf24dba5fb0_03.jpg"},
+		{"{date}_{time}", 1, 1, "2024-03-09_140506.jpg"},
+		{"{date:20060102}", 1, 1, "20240309.jpg"},
+		{"{parent}_{name}", 1, 1, "trip_IMG_0042.jpg"},
+		{"{name}.{ext}.bak", 1, 1, "IMG_0042.jpg.bak"},
+		{"photo_{n:04}", 1, 1, "photo_0001.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseTemplate(%q): %v", tt.template, err)
+			}
+			got, err := tmpl.render(tt.n, tt.total, file)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("render(%d, %d) = %q, want %q", tt.n, tt.total, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderSanitizesOriginalName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Bob's: trip")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := fileInfo{path: filepath.Join(dir, "It's 10:30.jpg"), ext: ".jpg"}
+
+	// 原名称中的 ' 和 : 替换为下划线, 而不是让整批重命名失败
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{name}", "It_s 10_30.jpg"},
+		{"{parent}_{n}", "Bob_s_ trip_1.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseTemplate(%q): %v", tt.template, err)
+			}
+			got, err := tmpl.render(1, 1, file)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderInvalidName(t *testing.T) {
+	tmpl, err := parseTemplate("{name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模板本身合法, 但生成的名称以临时后缀结尾
+	file := fileInfo{path: "/photos/a.tmrn-tmp"}
+	if _, err := tmpl.render(1, 1, file); err == nil {
+		t.Error("render succeeded for a name ending in the temporary suffix")
+	}
+}
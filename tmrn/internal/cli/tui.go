@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// 终端控制序列
+const (
+	escAltScreen  = "\x1b[?1049h" // 切换到备用屏幕, 退出后恢复原来的内容
+	escMainScreen = "\x1b[?1049l"
+	escHideCursor = "\x1b[?25l"
+	escShowCursor = "\x1b[?25h"
+	escHome       = "\x1b[H"
+	escClearLine  = "\x1b[K"
+	escClearBelow = "\x1b[J"
+	escReverse    = "\x1b[7m"
+	escReset      = "\x1b[0m"
+)
+
+// escKeys 方向键等按键的转义序列 (去掉开头的 ESC [ 或 ESC O) 到按键名称的映射
+var escKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left",
+	"1;2A": "shift-up", "1;2B": "shift-down",
+	"H": "home", "1~": "home", "7~": "home",
+	"F": "end", "4~": "end", "8~": "end",
+	"5~": "pgup", "6~": "pgdn", "3~": "delete",
+}
+
+// screen 全屏终端界面, 进入时切换到原始模式和备用屏幕, close 时恢复
+type screen struct {
+	state *term.State
+}
+
+// openScreen 进入全屏模式
+func openScreen() (*screen, error) {
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("无法进入交互模式: %w", err)
+	}
+	fmt.Print(escAltScreen + escHideCursor)
+	return &screen{state: state}, nil
+}
+
+// close 恢复终端原来的状态
+func (s *screen) close() {
+	fmt.Print(escShowCursor + escMainScreen)
+	_ = term.Restore(int(os.Stdin.Fd()), s.state)
+}
+
+// size 返回终端的列数和行数
+func (s *screen) size() (width, height int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return width, height
+}
+
+// draw 一次性输出整屏内容, 原始模式下换行需要 \r\n
+func (s *screen) draw(lines []string) {
+	var b strings.Builder
+	b.WriteString(escHome)
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString(escClearLine)
+	}
+	b.WriteString(escClearBelow)
+	fmt.Print(b.String())
+}
+
+// readKey 读取一个按键, 返回按键名称 (如 "up", "enter") 或输入的字符
+func readKey() (string, error) {
+	c, err := stdin.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	switch c {
+	case '\r', '\n':
+		return "enter", nil
+	case 0x7f, 0x08:
+		return "backspace", nil
+	case 0x03:
+		return "ctrl-c", nil
+	case 0x15:
+		return "ctrl-u", nil
+	case '\t':
+		return "tab", nil
+	case 0x1b:
+		// 单独按下 ESC 时后面没有其他字节
+		if stdin.Buffered() == 0 {
+			return "esc", nil
+		}
+		return readEscape()
+	}
+
+	if err := stdin.UnreadByte(); err != nil {
+		return "", err
+	}
+	r, _, err := stdin.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	return string(r), nil
+}
+
+// readEscape 读取 ESC 之后的控制序列, 不认识的序列返回空字符串
+func readEscape() (string, error) {
+	c, err := stdin.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if c != '[' && c != 'O' {
+		return "", nil
+	}
+
+	// 参数字节之后以 0x40-0x7e 之间的字节结束
+	var seq []byte
+	for {
+		c, err := stdin.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, c)
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+	return escKeys[string(seq)], nil
+}
+
+// runeWidth 字符在终端中占用的列数, 中日韩文字和全角字符占两列
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x1100:
+		return 1
+	case r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
+// textWidth 文本在终端中占用的列数
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// fitText 将文本截断或用空格补齐到正好 width 列, 截断时以 "…" 结尾
+func fitText(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if w := textWidth(s); w <= width {
+		return s + strings.Repeat(" ", width-w)
+	}
+
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		if used+runeWidth(r) > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += runeWidth(r)
+	}
+	b.WriteString("…")
+	return b.String() + strings.Repeat(" ", width-1-used)
+}
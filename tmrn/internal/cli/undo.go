@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// UndoRunner 存储 undo 子命令的选项参数
+type UndoRunner struct {
+	ID   int  // 要撤销的批次编号, 0 表示最近一个未撤销的批次
+	List bool // 只列出批次
+}
+
+// NewUndoRunner 构造函数
+func NewUndoRunner() *UndoRunner {
+	return &UndoRunner{}
+}
+
+// Validate 校验参数
+func (r *UndoRunner) Validate() error {
+	if r.ID < 0 {
+		return fmt.Errorf("批次编号无效 -> %d", r.ID)
+	}
+	return nil
+}
+
+// Run 列出批次, 或将一个批次中的文件恢复为原来的名称
+func (r *UndoRunner) Run() error {
+	dir, err := journalDir()
+	if err != nil {
+		return err
+	}
+	ids, err := listJournals(dir)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		warnColor.Printf("没有可撤销的批次\n")
+		return nil
+	}
+
+	if r.List {
+		return listBatches(dir, ids)
+	}
+
+	j, err := r.pickJournal(dir, ids)
+	if err != nil {
+		return err
+	}
+	if j.Undone != nil {
+		return fmt.Errorf("批次 %d 已于 %s 撤销", j.ID, j.Undone.Format("2006-01-02 15:04:05"))
+	}
+
+	fmt.Printf("正在撤销批次 %d: ", j.ID)
+	noticeColor.Printf("%s\n\n", j.Dir)
+
+	if j.Pending {
+		warnColor.Printf("注意: 批次 %d 没有正常完成, 若有残留的临时文件请先运行 'tmrn recover'\n\n", j.ID)
+	}
+
+	// 1. 确认文件在批次之后没有被修改或替换, 已恢复原名称的文件 (如上次撤销中断) 直接跳过
+	pending, restored, problems := checkJournal(j)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			errorColor.Printf("! %s\n", problem)
+		}
+		return errors.New("文件在重命名之后发生了变化, 拒绝撤销")
+	}
+	if restored > 0 {
+		skipColor.Printf("%d 个文件已是原名称, 将跳过\n\n", restored)
+	}
+	if len(pending) == 0 {
+		successColor.Printf("批次中的文件都已是原名称\n")
+		return j.markUndone(dir)
+	}
+
+	// 2. 反向执行批次中的重命名
+	plan := make([]renameOp, len(pending))
+	for i, e := range pending {
+		plan[i] = newRenameOp(e.Final, e.Original)
+	}
+	printPlan(plan, nil, planPageSize())
+	if !askForConfirmation("是否恢复 %d 个文件的原名称?", len(plan)) {
+		warnColor.Printf("操作已取消\n")
+		return nil
+	}
+
+	results, err := executePlan(plan)
+	if err != nil {
+		return fmt.Errorf("撤销时出错: %w", err)
+	}
+	for _, result := range results {
+		successColor.Printf("%s -> %s\n", filepath.Base(result.originalPath), filepath.Base(result.finalPath))
+	}
+	fmt.Printf("\n一共恢复 %d/%d 个文件\n", len(results), len(plan))
+
+	// 部分文件恢复失败时保留批次, 便于处理后再次撤销
+	if len(results) < len(plan) {
+		return errors.New("部分文件未能恢复, 批次未标记为已撤销")
+	}
+	return j.markUndone(dir)
+}
+
+// pickJournal 读取指定的批次, 未指定时选择最近一个未撤销的批次
+func (r *UndoRunner) pickJournal(dir string, ids []int) (*journal, error) {
+	if r.ID != 0 {
+		return loadJournal(dir, r.ID)
+	}
+
+	for i := len(ids) - 1; i >= 0; i-- {
+		j, err := loadJournal(dir, ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if j.Undone == nil {
+			return j, nil
+		}
+	}
+	return nil, errors.New("所有批次都已撤销")
+}
+
+// checkJournal 检查批次中每个文件的状态
+// 返回仍处于重命名之后状态、需要恢复的条目, 已经位于原名称的文件数, 以及发现的问题
+func checkJournal(j *journal) (pending []journalEntry, restored int, problems []string) {
+	finals := make(map[string]bool, len(j.Entries))
+	for _, e := range j.Entries {
+		finals[e.Final] = true
+	}
+
+	for _, e := range j.Entries {
+		info, err := os.Lstat(e.Final)
+		if err != nil || !e.matches(info) {
+			// 文件已经回到原名称时不需要再恢复
+			if original, err := os.Lstat(e.Original); err == nil && e.matches(original) {
+				restored++
+				continue
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s 已不存在", filepath.Base(e.Final)))
+			} else {
+				problems = append(problems, fmt.Sprintf("%s 已被修改或替换", filepath.Base(e.Final)))
+			}
+			continue
+		}
+
+		// 原名称被批次之外的文件占用时, 恢复会覆盖该文件
+		pending = append(pending, e)
+		if finals[e.Original] {
+			continue
+		}
+		if _, err := os.Lstat(e.Original); err == nil {
+			problems = append(problems, fmt.Sprintf("原名称 %s 已被其他文件占用", filepath.Base(e.Original)))
+		}
+	}
+
+	return pending, restored, problems
+}
+
+// listBatches 列出所有批次的编号、时间、目录和状态
+func listBatches(dir string, ids []int) error {
+	for _, id := range ids {
+		j, err := loadJournal(dir, id)
+		if err != nil {
+			return err
+		}
+
+		line := fmt.Sprintf("%4d  %s  %3d 个文件  %s", j.ID, j.Time.Format("2006-01-02 15:04:05"), len(j.Entries), j.Dir)
+		switch {
+		case j.Undone != nil:
+			skipColor.Printf("%s  (已撤销)\n", line)
+		case j.Pending:
+			warnColor.Printf("%s  (未完成)\n", line)
+		default:
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import "path/filepath"
+
+// UnshuffleRunner 存储 unshuffle 子命令的选项参数, 目录相关的选项与默认命令共用
+type UnshuffleRunner struct {
+	Runner
+}
+
+// NewUnshuffleRunner 构造函数, 文件按名称排序显示
+func NewUnshuffleRunner() *UnshuffleRunner {
+	r := &UnshuffleRunner{Runner: *NewRunner()}
+	r.SortBy = sortByName
+	return r
+}
+
+// Run 去掉随机模式添加的 4 位前缀, 恢复原来的文件名
+func (r *UnshuffleRunner) Run() error {
+	r.printHeader()
+
+	dirs, err := r.findDirs()
+	if err != nil {
+		return err
+	}
+	if err := checkLeftovers(dirs); err != nil {
+		return err
+	}
+
+	var plan []renameOp
+	for _, dir := range dirs {
+		files, _, err := r.findFiles(dir)
+		if err != nil {
+			warnColor.Printf("注意: %v\n", err)
+			continue
+		}
+
+		dirPlan, err := r.planWithPolicy(files, nil, r.planUnshuffle)
+		if err != nil {
+			return err
+		}
+		plan = append(plan, dirPlan...)
+	}
+
+	if len(plan) == 0 {
+		warnColor.Printf("没有发现带有随机前缀的文件\n")
+		return nil
+	}
+
+	return r.applyPlan(plan)
+}
+
+// planUnshuffle 为带有随机前缀的文件生成去掉前缀的计划
+func (r *UnshuffleRunner) planUnshuffle(files []fileInfo) ([]renameOp, error) {
+	r.sortFiles(files)
+	stripped := stripShufflePrefixes(files, r.Legacy)
+
+	var plan []renameOp
+	for i, file := range files {
+		if stripped[i] != filepath.Base(file.path) {
+			plan = append(plan, newRenameOp(file.path, filepath.Join(filepath.Dir(file.path), stripped[i])))
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,293 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// maxBoxRead 一次读入内存的 box 内容上限, 只用于 iinf/iloc 等元数据 box
+const maxBoxRead = 1 << 20
+
+// mp4Epoch MP4/MOV 时间字段的起点
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var errInvalidBox = errors.New("无效的 box 结构")
+
+// box ISO BMFF (MP4/MOV/HEIF) 中的一个 box, start 和 end 为内容部分的范围 (不含头部)
+type box struct {
+	typ        string
+	start, end int64
+}
+
+// isBMFF 根据文件开头的第一个 box 判断是否为 ISO BMFF 文件, 较旧的 MOV 可能没有 ftyp
+// 除 ftyp 和 moov 外, 其他 box 类型还要求长度字段的最高字节为 0, 避免把恰好含有这些单词的文本误判
+func isBMFF(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+	switch string(head[4:8]) {
+	case "ftyp", "moov":
+		return true
+	case "mdat", "wide", "free", "skip":
+		return head[0] == 0
+	}
+	return false
+}
+
+// readBMFF 读取 HEIF 中的 EXIF 数据, 没有拍摄时间时再读取 MP4/MOV 的创建时间
+func readBMFF(r io.ReaderAt, size int64) (Info, error) {
+	top, err := listBoxes(r, 0, size)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	if meta, ok := findBox(top, "meta"); ok {
+		if info, err = readHEIFExif(r, meta); err != nil {
+			return Info{}, err
+		}
+	}
+	if info.Time.IsZero() {
+		if moov, ok := findBox(top, "moov"); ok {
+			if info.Time, err = readMovieTime(r, moov); err != nil {
+				return Info{}, err
+			}
+		}
+	}
+	return info, nil
+}
+
+// listBoxes 列出 [start, end) 范围内的所有 box
+func listBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	var header [16]byte
+	for off := start; off+8 <= end; {
+		if _, err := r.ReadAt(header[:8], off); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		typ := string(header[4:8])
+		headerLen := int64(8)
+
+		switch size {
+		case 0: // 延伸到末尾
+			size = end - off
+		case 1: // 64 位长度
+			if _, err := r.ReadAt(header[8:16], off+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || size > end-off {
+			return nil, errInvalidBox
+		}
+
+		boxes = append(boxes, box{typ: typ, start: off + headerLen, end: off + size})
+		off += size
+	}
+	return boxes, nil
+}
+
+// findBox 返回第一个指定类型的 box
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// readContent 读取 box 的全部内容
+func readContent(r io.ReaderAt, b box) ([]byte, error) {
+	if b.end-b.start > maxBoxRead {
+		return nil, errInvalidBox
+	}
+	buf := make([]byte, b.end-b.start)
+	if _, err := r.ReadAt(buf, b.start); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readHEIFExif 在 HEIF 的 meta box 中查找类型为 Exif 的项目并解析
+func readHEIFExif(r io.ReaderAt, meta box) (Info, error) {
+	// meta 是 full box, 内容前有 4 字节的 version 和 flags
+	children, err := listBoxes(r, meta.start+4, meta.end)
+	if err != nil {
+		return Info{}, err
+	}
+	iinf, ok1 := findBox(children, "iinf")
+	iloc, ok2 := findBox(children, "iloc")
+	if !ok1 || !ok2 {
+		return Info{}, nil
+	}
+
+	id, ok, err := findExifItem(r, iinf)
+	if err != nil || !ok {
+		return Info{}, err
+	}
+	offset, length, ok, err := locateItem(r, iloc, id)
+	if err != nil || !ok {
+		return Info{}, err
+	}
+
+	// Exif 项目以 4 字节的 TIFF 头偏移开始
+	var prefix [4]byte
+	if length < 4 {
+		return Info{}, errInvalidBox
+	}
+	if _, err := r.ReadAt(prefix[:], offset); err != nil {
+		return Info{}, err
+	}
+	skip := 4 + int64(binary.BigEndian.Uint32(prefix[:]))
+	if skip >= length {
+		return Info{}, errInvalidBox
+	}
+	return readTIFF(io.NewSectionReader(r, offset+skip, length-skip))
+}
+
+// findExifItem 在 iinf box 中查找 Exif 项目的编号
+func findExifItem(r io.ReaderAt, iinf box) (uint32, bool, error) {
+	var head [8]byte
+	if _, err := r.ReadAt(head[:], iinf.start); err != nil {
+		return 0, false, err
+	}
+	entriesStart := iinf.start + 6
+	if head[0] != 0 {
+		entriesStart = iinf.start + 8
+	}
+
+	entries, err := listBoxes(r, entriesStart, iinf.end)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, e := range entries {
+		if e.typ != "infe" || e.end-e.start < 12 {
+			continue
+		}
+		buf := make([]byte, min(e.end-e.start, 14))
+		if _, err := r.ReadAt(buf, e.start); err != nil {
+			return 0, false, err
+		}
+		// version 2 的项目编号为 16 位, version 3 为 32 位, 之后是 16 位保护索引和 4 字节类型
+		switch {
+		case buf[0] == 2:
+			if string(buf[8:12]) == "Exif" {
+				return uint32(binary.BigEndian.Uint16(buf[4:6])), true, nil
+			}
+		case buf[0] == 3 && len(buf) == 14:
+			if string(buf[10:14]) == "Exif" {
+				return binary.BigEndian.Uint32(buf[4:8]), true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+// locateItem 在 iloc box 中查找项目数据在文件中的位置, 只支持按文件偏移存储的项目
+func locateItem(r io.ReaderAt, iloc box, id uint32) (offset, length int64, ok bool, err error) {
+	buf, err := readContent(r, iloc)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	p := &byteParser{buf: buf}
+
+	version := p.uint(1)
+	p.skip(3)
+	sizes := p.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = p.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0x0F)
+	if version == 0 {
+		indexSize = 0
+	}
+
+	var itemCount uint64
+	if version < 2 {
+		itemCount = p.uint(2)
+	} else {
+		itemCount = p.uint(4)
+	}
+
+	for i := uint64(0); i < itemCount && p.err == nil; i++ {
+		var itemID uint64
+		if version < 2 {
+			itemID = p.uint(2)
+		} else {
+			itemID = p.uint(4)
+		}
+		method := uint64(0)
+		if version == 1 || version == 2 {
+			method = p.uint(2) & 0x0F
+		}
+		p.skip(2) // data_reference_index
+		baseOffset := p.uint(baseOffsetSize)
+		extentCount := p.uint(2)
+
+		for j := uint64(0); j < extentCount && p.err == nil; j++ {
+			p.skip(indexSize)
+			extentOffset := p.uint(offsetSize)
+			extentLength := p.uint(lengthSize)
+			if j == 0 && uint32(itemID) == id && method == 0 && p.err == nil {
+				return int64(baseOffset + extentOffset), int64(extentLength), true, nil
+			}
+		}
+	}
+	return 0, 0, false, p.err
+}
+
+// readMovieTime 读取 moov/mvhd 中的创建时间
+func readMovieTime(r io.ReaderAt, moov box) (time.Time, error) {
+	children, err := listBoxes(r, moov.start, moov.end)
+	if err != nil {
+		return time.Time{}, err
+	}
+	mvhd, ok := findBox(children, "mvhd")
+	if !ok || mvhd.end-mvhd.start < 12 {
+		return time.Time{}, nil
+	}
+
+	var buf [12]byte
+	if _, err := r.ReadAt(buf[:], mvhd.start); err != nil {
+		return time.Time{}, err
+	}
+	var secs uint64
+	if buf[0] == 1 {
+		secs = binary.BigEndian.Uint64(buf[4:12])
+	} else {
+		secs = uint64(binary.BigEndian.Uint32(buf[4:8]))
+	}
+	// 很多设备不设置创建时间, 写入 0
+	if secs == 0 {
+		return time.Time{}, nil
+	}
+	return mp4Epoch.Add(time.Duration(secs) * time.Second).Local(), nil
+}
+
+// byteParser 顺序读取大端序的变长整数, 越界后 err 不为空且后续读取都返回 0
+type byteParser struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (p *byteParser) uint(n int) uint64 {
+	if p.err != nil || p.pos+n > len(p.buf) {
+		p.err = errInvalidBox
+		return 0
+	}
+	var v uint64
+	for _, b := range p.buf[p.pos : p.pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	p.pos += n
+	return v
+}
+
+func (p *byteParser) skip(n int) {
+	p.uint(n)
+}
@@ -0,0 +1,75 @@
+// Package media 从照片和视频文件中读取拍摄时间等元数据
+// 支持 JPEG、TIFF (以及基于 TIFF 的 RAW 格式)、HEIC/HEIF 的 EXIF 信息和 MP4/MOV 的 mvhd 创建时间
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNoMetadata 文件格式不受支持或没有记录拍摄时间等信息
+var ErrNoMetadata = errors.New("没有找到元数据")
+
+// Info 从文件中读取到的元数据, 缺失的字段为零值
+type Info struct {
+	Time  time.Time // 拍摄时间 (EXIF DateTimeOriginal 或视频的创建时间)
+	Make  string    // 相机厂商
+	Model string    // 相机型号
+}
+
+// Camera 返回相机名称, 型号中已包含厂商名时不再重复
+func (i Info) Camera() string {
+	switch {
+	case i.Make == "":
+		return i.Model
+	case i.Model == "":
+		return i.Make
+	case strings.HasPrefix(strings.ToLower(i.Model), strings.ToLower(i.Make)):
+		return i.Model
+	default:
+		return i.Make + " " + i.Model
+	}
+}
+
+// Read 根据文件内容识别格式并读取元数据, 不依赖扩展名
+func Read(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+
+	var head [12]byte
+	if _, err := io.ReadFull(f, head[:]); err != nil {
+		return Info{}, ErrNoMetadata
+	}
+
+	var info Info
+	switch {
+	case bytes.HasPrefix(head[:], []byte{0xFF, 0xD8, 0xFF}):
+		info, err = readJPEG(f, stat.Size())
+	case bytes.HasPrefix(head[:], []byte("II*\x00")), bytes.HasPrefix(head[:], []byte("MM\x00*")):
+		info, err = readTIFF(io.NewSectionReader(f, 0, stat.Size()))
+	case isBMFF(head[:]):
+		info, err = readBMFF(f, stat.Size())
+	default:
+		return Info{}, ErrNoMetadata
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("解析元数据失败: %w", err)
+	}
+	if info == (Info{}) {
+		return Info{}, ErrNoMetadata
+	}
+	return info, nil
+}
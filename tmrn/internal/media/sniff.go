@@ -0,0 +1,187 @@
+package media
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Kind 根据文件内容判断的大类
+type Kind string
+
+const (
+	KindImage   Kind = "image"
+	KindVideo   Kind = "video"
+	KindAudio   Kind = "audio"
+	KindDoc     Kind = "doc"
+	KindUnknown Kind = ""
+)
+
+// Kinds 所有可用于筛选的大类
+var Kinds = []Kind{KindImage, KindVideo, KindAudio, KindDoc}
+
+// sniffLen 判断类型时读取的文件头长度, 与 http.DetectContentType 一致
+const sniffLen = 512
+
+// magic 固定偏移处的特征字节, container 不为空时文件还必须以它开头 (如 RIFF 容器)
+type magic struct {
+	container string
+	offset    int
+	sig       string
+	kind      Kind
+}
+
+// magics 常见格式的特征字节, 按顺序匹配
+var magics = []magic{
+	// 图片
+	{"", 0, "\xFF\xD8\xFF", KindImage},
+	{"", 0, "\x89PNG\r\n\x1a\n", KindImage},
+	{"", 0, "GIF87a", KindImage},
+	{"", 0, "GIF89a", KindImage},
+	{"", 0, "BM", KindImage},
+	{"", 0, "II*\x00", KindImage}, // TIFF 以及 DNG/NEF/CR2 等 RAW 格式
+	{"", 0, "MM\x00*", KindImage},
+	{"", 0, "IIRO", KindImage},    // Olympus ORF
+	{"", 0, "IIU\x00", KindImage}, // Panasonic RW2
+	{"", 0, "FUJIFILMCCD-RAW", KindImage},
+	{"", 0, "\x00\x00\x01\x00", KindImage}, // ICO
+	{"", 0, "8BPS", KindImage},
+	{"", 0, "\xFF\x0A", KindImage}, // JPEG XL
+	{"", 0, "\x00\x00\x00\x0CJXL ", KindImage},
+	{"RIFF", 8, "WEBP", KindImage},
+
+	// 视频
+	{"RIFF", 8, "AVI ", KindVideo},
+	{"", 0, "\x1A\x45\xDF\xA3", KindVideo}, // Matroska, WebM
+	{"", 0, "FLV", KindVideo},
+	{"", 0, "\x30\x26\xB2\x75\x8E\x66\xCF\x11", KindVideo}, // ASF, WMV
+	{"", 0, "\x00\x00\x01\xBA", KindVideo},                 // MPEG-PS
+	{"", 0, "\x00\x00\x01\xB3", KindVideo},
+
+	// 音频
+	{"", 0, "ID3", KindAudio},
+	{"", 0, "fLaC", KindAudio},
+	{"", 0, "OggS", KindAudio},
+	{"RIFF", 8, "WAVE", KindAudio},
+	{"FORM", 8, "AIFF", KindAudio},
+	{"FORM", 8, "AIFC", KindAudio},
+	{"", 0, "MThd", KindAudio},
+	{"", 0, "#!AMR", KindAudio},
+	{"", 0, "wvpk", KindAudio},
+	{"", 0, "MAC ", KindAudio},
+	{"", 0, ".snd", KindAudio},
+
+	// 文档
+	{"", 0, "%PDF-", KindDoc},
+	{"", 0, "\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1", KindDoc}, // 旧版 Office
+	{"", 0, "{\\rtf", KindDoc},
+	{"", 0, "AT&TFORM", KindDoc}, // DjVu
+}
+
+// heifBrands HEIF 图片的 ftyp 品牌, 其余 ftyp 文件按品牌区分音频和视频
+var heifBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "hevm", "hevs", "mif1", "msf1", "avif", "avis"}
+
+// audioBrands 纯音频的 ftyp 品牌
+var audioBrands = []string{"M4A ", "M4B ", "M4P ", "F4A ", "F4B "}
+
+// DetectKind 读取文件头判断文件的大类, 不依赖扩展名
+func DetectKind(path string) (Kind, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return KindUnknown, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return KindUnknown, err
+	}
+	return Sniff(head[:n]), nil
+}
+
+// Sniff 根据文件开头的内容判断文件的大类
+func Sniff(head []byte) Kind {
+	if len(head) == 0 {
+		return KindUnknown
+	}
+
+	if len(head) >= 12 && isBMFF(head) {
+		return sniffBMFF(head)
+	}
+	for _, m := range magics {
+		if !bytes.HasPrefix(head, []byte(m.container)) || len(head) < m.offset+len(m.sig) {
+			continue
+		}
+		if string(head[m.offset:m.offset+len(m.sig)]) == m.sig {
+			return m.kind
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return sniffZip(head)
+	case len(head) > 188 && head[0] == 0x47 && head[188] == 0x47:
+		// MPEG-TS 每 188 字节一个以 0x47 开头的包
+		return KindVideo
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		// 没有 ID3 标签的 MP3 和 ADTS 格式的 AAC 以帧同步字开头
+		return KindAudio
+	}
+
+	// 其余交给标准库判断, 纯文本视为文档
+	contentType := http.DetectContentType(head)
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return KindImage
+	case strings.HasPrefix(contentType, "video/"):
+		return KindVideo
+	case strings.HasPrefix(contentType, "audio/"):
+		return KindAudio
+	case strings.HasPrefix(contentType, "text/"), contentType == "application/pdf", contentType == "application/postscript":
+		return KindDoc
+	}
+	return KindUnknown
+}
+
+// sniffBMFF 根据 ftyp 品牌区分 HEIF 图片、音频和视频, 没有 ftyp 的旧版 MOV 视为视频
+func sniffBMFF(head []byte) Kind {
+	if string(head[4:8]) != "ftyp" {
+		return KindVideo
+	}
+
+	brand := string(head[8:12])
+	switch {
+	case slices.Contains(heifBrands, brand):
+		return KindImage
+	case slices.Contains(audioBrands, brand):
+		return KindAudio
+	case strings.HasPrefix(brand, "crx"):
+		return KindImage // Canon CR3
+	}
+	return KindVideo
+}
+
+// sniffZip 识别以 zip 打包的文档格式: Office Open XML、OpenDocument 和 EPUB
+func sniffZip(head []byte) Kind {
+	// 第一个文件名位于本地文件头的第 30 字节处
+	if len(head) < 30 {
+		return KindUnknown
+	}
+	rest := string(head[30:])
+	switch {
+	case strings.HasPrefix(rest, "[Content_Types].xml"),
+		strings.HasPrefix(rest, "_rels/"),
+		strings.HasPrefix(rest, "word/"),
+		strings.HasPrefix(rest, "xl/"),
+		strings.HasPrefix(rest, "ppt/"),
+		strings.HasPrefix(rest, "docProps/"),
+		strings.HasPrefix(rest, "mimetypeapplication/vnd.oasis.opendocument"),
+		strings.HasPrefix(rest, "mimetypeapplication/epub+zip"):
+		return KindDoc
+	}
+	return KindUnknown
+}
@@ -0,0 +1,213 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// 用到的 TIFF/EXIF 标签
+const (
+	tagMake              = 0x010F
+	tagModel             = 0x0110
+	tagExifIFD           = 0x8769
+	tagDateTimeOriginal  = 0x9003
+	tagDateTimeDigitized = 0x9004
+	tagOffsetTimeOrig    = 0x9011
+)
+
+// TIFF 字段类型
+const (
+	typeASCII = 2
+	typeShort = 3
+)
+
+// 防止损坏的文件导致读取过多数据
+const (
+	maxIFDEntries = 1024
+	maxASCIILen   = 256
+)
+
+var errInvalidTIFF = errors.New("无效的 TIFF 数据")
+
+// ifdEntry IFD 中的一个条目, value 为原始的 4 字节值或数据偏移
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value [4]byte
+}
+
+// tiffReader 按 TIFF 头声明的字节序读取 IFD
+type tiffReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// readJPEG 在 JPEG 的 APP1 段中查找 EXIF 数据, 读到图像数据 (SOS) 时停止
+func readJPEG(r io.ReaderAt, size int64) (Info, error) {
+	var marker [4]byte
+	for off := int64(2); off+4 <= size; {
+		if _, err := r.ReadAt(marker[:], off); err != nil {
+			return Info{}, err
+		}
+		if marker[0] != 0xFF {
+			return Info{}, errors.New("无效的 JPEG 段")
+		}
+		switch m := marker[1]; {
+		case m == 0xFF: // 填充字节
+			off++
+			continue
+		case m == 0x01 || (m >= 0xD0 && m <= 0xD8): // 没有长度的独立标记
+			off += 2
+			continue
+		case m == 0xDA || m == 0xD9: // SOS, EOI
+			return Info{}, nil
+		}
+
+		length := int64(binary.BigEndian.Uint16(marker[2:]))
+		if length < 2 {
+			return Info{}, errors.New("无效的 JPEG 段长度")
+		}
+		if marker[1] == 0xE1 && length >= 2+6+8 {
+			var ident [6]byte
+			if _, err := r.ReadAt(ident[:], off+4); err != nil {
+				return Info{}, err
+			}
+			// APP1 也用于 XMP, 只处理 EXIF
+			if bytes.Equal(ident[:], []byte("Exif\x00\x00")) {
+				return readTIFF(io.NewSectionReader(r, off+4+6, length-2-6))
+			}
+		}
+		off += 2 + length
+	}
+	return Info{}, nil
+}
+
+// readTIFF 从 TIFF 结构中读取相机信息和拍摄时间
+func readTIFF(r io.ReaderAt) (Info, error) {
+	var header [8]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return Info{}, errInvalidTIFF
+	}
+
+	t := tiffReader{r: r}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return Info{}, errInvalidTIFF
+	}
+	if t.order.Uint16(header[2:]) != 42 {
+		return Info{}, errInvalidTIFF
+	}
+
+	ifd0, err := t.readIFD(int64(t.order.Uint32(header[4:])))
+	if err != nil {
+		return Info{}, err
+	}
+
+	info := Info{
+		Make:  t.ascii(ifd0[tagMake]),
+		Model: t.ascii(ifd0[tagModel]),
+	}
+
+	// 拍摄时间位于 EXIF 子 IFD 中
+	if e, ok := ifd0[tagExifIFD]; ok {
+		exif, err := t.readIFD(t.offset(e))
+		if err != nil {
+			return info, nil
+		}
+		original := t.ascii(exif[tagDateTimeOriginal])
+		if original == "" {
+			original = t.ascii(exif[tagDateTimeDigitized])
+		}
+		info.Time = parseExifTime(original, t.ascii(exif[tagOffsetTimeOrig]))
+	}
+
+	return info, nil
+}
+
+// readIFD 读取 off 处的 IFD, 返回标签到条目的映射
+func (t tiffReader) readIFD(off int64) (map[uint16]ifdEntry, error) {
+	var countBuf [2]byte
+	if _, err := t.r.ReadAt(countBuf[:], off); err != nil {
+		return nil, errInvalidTIFF
+	}
+	count := int(t.order.Uint16(countBuf[:]))
+	if count > maxIFDEntries {
+		return nil, errInvalidTIFF
+	}
+
+	buf := make([]byte, count*12)
+	if _, err := t.r.ReadAt(buf, off+2); err != nil {
+		return nil, errInvalidTIFF
+	}
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := range count {
+		b := buf[i*12:]
+		var e ifdEntry
+		e.typ = t.order.Uint16(b[2:])
+		e.count = t.order.Uint32(b[4:])
+		copy(e.value[:], b[8:12])
+		entries[t.order.Uint16(b)] = e
+	}
+	return entries, nil
+}
+
+// ascii 读取 ASCII 类型条目的字符串值, 类型不符或读取失败时返回空串
+func (t tiffReader) ascii(e ifdEntry) string {
+	if e.typ != typeASCII || e.count == 0 || e.count > maxASCIILen {
+		return ""
+	}
+
+	var data []byte
+	if e.count <= 4 {
+		data = e.value[:e.count]
+	} else {
+		data = make([]byte, e.count)
+		if _, err := t.r.ReadAt(data, int64(t.order.Uint32(e.value[:]))); err != nil {
+			return ""
+		}
+	}
+
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// offset 读取指向子 IFD 的偏移
+func (t tiffReader) offset(e ifdEntry) int64 {
+	if e.typ == typeShort {
+		return int64(t.order.Uint16(e.value[:]))
+	}
+	return int64(t.order.Uint32(e.value[:]))
+}
+
+// parseExifTime 解析 "2006:01:02 15:04:05" 格式的 EXIF 时间
+// EXIF 时间本身不含时区, 有 OffsetTimeOriginal 时使用该时区, 否则按本地时间处理
+func parseExifTime(value, offset string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	loc := time.Local
+	if tz, err := time.Parse("-07:00", offset); err == nil {
+		_, secs := tz.Zone()
+		loc = time.FixedZone(offset, secs)
+	}
+
+	for _, layout := range []string{"2006:01:02 15:04:05", "2006-01-02 15:04:05", "2006:01:02T15:04:05"} {
+		if ts, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return ts
+		}
+	}
+	// 未设置的时间通常写为 "0000:00:00 00:00:00", 解析失败即视为缺失
+	return time.Time{}
+}